@@ -21,6 +21,7 @@ import (
 
 	"github.com/kubernetes/kompose/pkg/app"
 	"github.com/kubernetes/kompose/pkg/kobject"
+	"github.com/kubernetes/kompose/pkg/kompose"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -48,6 +49,18 @@ var (
 	ConvertPushImage             bool
 	ConvertOpt                   kobject.ConvertOptions
 	ConvertYAMLIndent            int
+	ConvertProfiles              []string
+	ConvertWaitForDependencies   bool
+	ConvertWaitImage             string
+	ConvertEnvFiles              []string
+	ConvertReport                string
+	ConvertFormat                string
+	ConvertOverlays              map[string]string
+	ConvertNamespace             string
+	ConvertCreateNamespace       bool
+	ConvertKubeVersion           string
+	ConvertPDB                   bool
+	ConvertGPUResource           string
 
 	UpBuild string
 
@@ -100,15 +113,37 @@ var convertCmd = &cobra.Command{
 			YAMLIndent:                  ConvertYAMLIndent,
 			WithKomposeAnnotation:       WithKomposeAnnotation,
 			MultipleContainerMode:       MultipleContainerMode,
+			Profiles:                    ConvertProfiles,
+			WaitForDependencies:         ConvertWaitForDependencies,
+			WaitForDependenciesImage:    ConvertWaitImage,
+			EnvFiles:                    ConvertEnvFiles,
+			Report:                      ConvertReport,
+			Format:                      strings.ToLower(ConvertFormat),
+			Overlays:                    ConvertOverlays,
+			Namespace:                   ConvertNamespace,
+			IsNamespaceFlag:             cmd.Flags().Lookup("namespace").Changed,
+			CreateNamespace:             ConvertCreateNamespace,
+			KubeVersion:                 ConvertKubeVersion,
+			CreatePDB:                   ConvertPDB,
+			GPUResource:                 ConvertGPUResource,
 		}
 
 		// Validate before doing anything else. Use "bundle" if passed in.
-		app.ValidateFlags(GlobalBundle, args, cmd, &ConvertOpt)
-		app.ValidateComposeFile(&ConvertOpt)
+		if err := app.ValidateFlags(GlobalBundle, args, cmd, &ConvertOpt); err != nil {
+			log.Fatal(err)
+		}
+		if err := app.ValidateComposeFile(&ConvertOpt); err != nil {
+			log.Fatal(err)
+		}
+		if err := kompose.ValidateOptions(&ConvertOpt); err != nil {
+			log.Fatal(err)
+		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 
-		app.Convert(ConvertOpt)
+		if err := app.Convert(ConvertOpt); err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
@@ -121,7 +156,7 @@ func init() {
 	convertCmd.Flags().BoolVar(&ConvertDaemonSet, "daemon-set", false, "Generate a Kubernetes daemonset object (deprecated, use --controller instead)")
 	convertCmd.Flags().BoolVarP(&ConvertDeployment, "deployment", "d", false, "Generate a Kubernetes deployment object (deprecated, use --controller instead)")
 	convertCmd.Flags().BoolVar(&ConvertReplicationController, "replication-controller", false, "Generate a Kubernetes replication controller object (deprecated, use --controller instead)")
-	convertCmd.Flags().StringVar(&ConvertController, "controller", "", `Set the output controller ("deployment"|"daemonSet"|"replicationController")`)
+	convertCmd.Flags().StringVar(&ConvertController, "controller", "", `Set the output controller ("deployment"|"daemonSet"|"replicationController"|"statefulset"|"job")`)
 	convertCmd.Flags().MarkDeprecated("daemon-set", "use --controller")
 	convertCmd.Flags().MarkDeprecated("deployment", "use --controller")
 	convertCmd.Flags().MarkDeprecated("replication-controller", "use --controller")
@@ -162,6 +197,19 @@ func init() {
 
 	convertCmd.Flags().IntVar(&ConvertYAMLIndent, "indent", 2, "Spaces length to indent generated yaml files")
 
+	convertCmd.Flags().BoolVar(&ConvertWaitForDependencies, "wait-for-dependencies", false, "Generate init containers waiting for the services listed in depends_on")
+	convertCmd.Flags().StringVar(&ConvertWaitImage, "wait-for-dependencies-image", "", "Set the image of the init containers generated by --wait-for-dependencies, it needs sh and nc (default is busybox:1.36)")
+	convertCmd.Flags().StringArrayVar(&ConvertEnvFiles, "env-file", []string{}, "Specify an env file holding the variables to interpolate (default is the .env file of the project directory)")
+	convertCmd.Flags().StringArrayVar(&ConvertProfiles, "profile", []string{}, `Specify a profile to enable, "*" enables all of them (default is $COMPOSE_PROFILES)`)
+	convertCmd.Flags().StringVar(&ConvertFormat, "format", "", `Set the layout of the output files ("kustomize" writes a Kustomize base and overlays to the --out directory)`)
+	convertCmd.Flags().StringToStringVar(&ConvertOverlays, "overlay", map[string]string{}, "Add a Kustomize overlay NAME=FILE, patching the base with the objects of the input files overridden by FILE (requires --format kustomize)")
+	convertCmd.Flags().StringVarP(&ConvertNamespace, "namespace", "n", "", "Set the namespace of the generated resources (default is the name of the compose project, if any)")
+	convertCmd.Flags().BoolVar(&ConvertCreateNamespace, "create-namespace", false, "Generate the Namespace (or OpenShift Project) of the generated resources")
+	convertCmd.Flags().StringVar(&ConvertKubeVersion, "kube-version", "", "Set the Kubernetes version the objects are generated for, like 1.21 (default is the latest version)")
+	convertCmd.Flags().BoolVar(&ConvertPDB, "pdb", false, "Generate a PodDisruptionBudget for the Deployments and StatefulSets with more than one replica")
+	convertCmd.Flags().StringVar(&ConvertGPUResource, "gpu-resource", "nvidia.com/gpu", "Set the extended resource requested for the GPUs reserved by deploy.resources.reservations.devices, like amd.com/gpu")
+	convertCmd.Flags().StringVar(&ConvertReport, "report", "", "Write a report of the dropped, approximated and renamed fields to a file (JSON if it ends with .json, YAML otherwise)")
+
 	// In order to 'separate' both OpenShift and Kubernetes only flags. A custom help page is created
 	customHelp := `Usage:{{if .Runnable}}
   {{if .HasAvailableFlags}}{{appendIfNotPresent .UseLine "[flags]"}}{{else}}{{.UseLine}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
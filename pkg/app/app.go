@@ -17,23 +17,25 @@ limitations under the License.
 package app
 
 import (
+	"context"
 	"strings"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"os"
 
 	"github.com/kubernetes/kompose/pkg/kobject"
-	"github.com/kubernetes/kompose/pkg/loader"
-	"github.com/kubernetes/kompose/pkg/transformer"
-	"github.com/kubernetes/kompose/pkg/transformer/kubernetes"
-	"github.com/kubernetes/kompose/pkg/transformer/openshift"
+	"github.com/kubernetes/kompose/pkg/kompose"
 )
 
 var (
-	// DefaultComposeFiles is a list of filenames that kompose will use if no file is explicitly set
+	// DefaultComposeFiles is a list of filenames that kompose will use if no file is explicitly set,
+	// the Compose Specification names come before the legacy ones
 	DefaultComposeFiles = []string{
+		"compose.yaml",
+		"compose.yml",
 		"docker-compose.yml",
 		"docker-compose.yaml",
 		"container-compose.yml",
@@ -43,17 +45,15 @@ var (
 
 const (
 	// ProviderKubernetes is provider kubernetes
-	ProviderKubernetes = "kubernetes"
+	ProviderKubernetes = kompose.ProviderKubernetes
 	// ProviderOpenshift is provider openshift
-	ProviderOpenshift = "openshift"
+	ProviderOpenshift = kompose.ProviderOpenshift
 	// DefaultProvider - provider that will be used if there is no provider was explicitly set
-	DefaultProvider = ProviderKubernetes
+	DefaultProvider = kompose.DefaultProvider
 )
 
-var inputFormat = "compose"
-
 // ValidateFlags validates all command line flags
-func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobject.ConvertOptions) {
+func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobject.ConvertOptions) error {
 	// Check to see if the "file" has changed from the default flag value
 	isFileSet := cmd.Flags().Lookup("file").Changed
 
@@ -85,73 +85,52 @@ func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobjec
 	switch {
 	case provider == ProviderOpenshift:
 		if chart {
-			log.Fatalf("--chart, -c is a Kubernetes only flag")
+			return errors.New("--chart, -c is a Kubernetes only flag")
 		}
 		if daemonSet {
-			log.Fatalf("--daemon-set is a Kubernetes only flag")
+			return errors.New("--daemon-set is a Kubernetes only flag")
 		}
 		if replicationController {
-			log.Fatalf("--replication-controller is a Kubernetes only flag")
+			return errors.New("--replication-controller is a Kubernetes only flag")
 		}
 		if deployment {
-			log.Fatalf("--deployment, -d is a Kubernetes only flag")
+			return errors.New("--deployment, -d is a Kubernetes only flag")
 		}
-		if controller == "daemonset" || controller == "replicationcontroller" || controller == "deployment" {
-			log.Fatalf("--controller= daemonset, replicationcontroller or deployment is a Kubernetes only flag")
+		if controller == "daemonset" || controller == "replicationcontroller" || controller == "deployment" || controller == "statefulset" || controller == "job" {
+			return errors.New("--controller= daemonset, replicationcontroller, deployment, statefulset or job is a Kubernetes only flag")
 		}
 	case provider == ProviderKubernetes:
 		if deploymentConfig {
-			log.Fatalf("--deployment-config is an OpenShift only flag")
+			return errors.New("--deployment-config is an OpenShift only flag")
 		}
 		if buildRepo {
-			log.Fatalf("--build-repo is an Openshift only flag")
+			return errors.New("--build-repo is an Openshift only flag")
 		}
 		if buildBranch {
-			log.Fatalf("--build-branch is an Openshift only flag")
+			return errors.New("--build-branch is an Openshift only flag")
 		}
 		if controller == "deploymentconfig" {
-			log.Fatalf("--controller=deploymentConfig is an OpenShift only flag")
+			return errors.New("--controller=deploymentConfig is an OpenShift only flag")
 		}
 	}
 
-	// Standard checks regardless of provider
-	if len(opt.OutFile) != 0 && opt.ToStdout {
-		log.Fatalf("Error: --out and --stdout can't be set at the same time")
-	}
-
-	if opt.CreateChart && opt.ToStdout {
-		log.Fatalf("Error: chart cannot be generated when --stdout is specified")
-	}
-
-	if opt.Replicas < 0 {
-		log.Fatalf("Error: --replicas cannot be negative")
-	}
-
 	if len(bundle) > 0 {
-		inputFormat = "bundle"
-		log.Fatalf("DAB / bundle (--bundle | -b) is no longer supported. See issue: https://github.com/kubernetes/kompose/issues/390")
-		opt.InputFiles = []string{bundle}
+		return errors.New("DAB / bundle (--bundle | -b) is no longer supported. See issue: https://github.com/kubernetes/kompose/issues/390")
 	}
 
 	if len(bundle) > 0 && isFileSet {
-		log.Fatalf("Error: 'compose' file and 'dab' file cannot be specified at the same time")
+		return errors.New("Error: 'compose' file and 'dab' file cannot be specified at the same time")
 	}
 
 	if len(args) != 0 {
-		log.Fatal("Unknown Argument(s): ", strings.Join(args, ","))
+		return errors.New("Unknown Argument(s): " + strings.Join(args, ","))
 	}
 
-	if opt.GenerateJSON && opt.GenerateYaml {
-		log.Fatalf("YAML and JSON format cannot be provided at the same time")
-	}
-
-	if opt.Volumes != "persistentVolumeClaim" && opt.Volumes != "emptyDir" && opt.Volumes != "hostPath" && opt.Volumes != "configMap" {
-		log.Fatal("Unknown Volume type: ", opt.Volumes, ", possible values are: persistentVolumeClaim, configMap and emptyDir")
-	}
+	return nil
 }
 
 // ValidateComposeFile validates the compose file provided for conversion
-func ValidateComposeFile(opt *kobject.ConvertOptions) {
+func ValidateComposeFile(opt *kobject.ConvertOptions) error {
 	if len(opt.InputFiles) == 0 {
 		for _, name := range DefaultComposeFiles {
 			_, err := os.Stat(name)
@@ -159,102 +138,17 @@ func ValidateComposeFile(opt *kobject.ConvertOptions) {
 				log.Debugf("'%s' not found: %v", name, err)
 			} else {
 				opt.InputFiles = []string{name}
-				return
-			}
-		}
-
-		log.Fatal("No 'docker-compose' file found")
-	}
-}
-
-func validateControllers(opt *kobject.ConvertOptions) {
-	singleOutput := len(opt.OutFile) != 0 || opt.OutFile == "-" || opt.ToStdout
-	if opt.Provider == ProviderKubernetes {
-		// create deployment by default if no controller has been set
-		if !opt.CreateD && !opt.CreateDS && !opt.CreateRC && opt.Controller == "" {
-			opt.CreateD = true
-		}
-		if singleOutput {
-			count := 0
-			if opt.CreateD {
-				count++
-			}
-			if opt.CreateDS {
-				count++
-			}
-			if opt.CreateRC {
-				count++
+				return nil
 			}
-			if count > 1 {
-				log.Fatalf("Error: only one kind of Kubernetes resource can be generated when --out or --stdout is specified")
-			}
-		}
-	} else if opt.Provider == ProviderOpenshift {
-		// create deploymentconfig by default if no controller has been set
-		if !opt.CreateDeploymentConfig {
-			opt.CreateDeploymentConfig = true
 		}
-		if singleOutput {
-			count := 0
-			if opt.CreateDeploymentConfig {
-				count++
-			}
-			// Add more controllers here once they are available in OpenShift
-			// if opt.foo {count++}
 
-			if count > 1 {
-				log.Fatalf("Error: only one kind of OpenShift resource can be generated when --out or --stdout is specified")
-			}
-		}
+		return errors.New("No 'docker-compose' file found")
 	}
+	return nil
 }
 
 // Convert transforms docker compose or dab file to k8s objects
-func Convert(opt kobject.ConvertOptions) {
-	validateControllers(&opt)
-
-	// loader parses input from file into komposeObject.
-	l, err := loader.GetLoader(inputFormat)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	komposeObject := kobject.KomposeObject{
-		ServiceConfigs: make(map[string]kobject.ServiceConfig),
-	}
-	komposeObject, err = l.LoadFile(opt.InputFiles)
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-
-	// Get a transformer that maps komposeObject to provider's primitives
-	t := getTransformer(opt)
-
-	// Do the transformation
-	objects, err := t.Transform(komposeObject, opt)
-
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-
-	// Print output
-	err = kubernetes.PrintList(objects, opt)
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-}
-
-// Convenience method to return the appropriate Transformer based on
-// what provider we are using.
-func getTransformer(opt kobject.ConvertOptions) transformer.Transformer {
-	var t transformer.Transformer
-	if opt.Provider == DefaultProvider {
-		// Create/Init new Kubernetes object with CLI opts
-		t = &kubernetes.Kubernetes{Opt: opt}
-	} else {
-		// Create/Init new OpenShift object that is initialized with a newly
-		// created Kubernetes object. Openshift inherits from Kubernetes
-		t = &openshift.OpenShift{Kubernetes: kubernetes.Kubernetes{Opt: opt}}
-	}
-	return t
+func Convert(opt kobject.ConvertOptions) error {
+	_, err := kompose.Convert(context.Background(), kompose.Options{ConvertOptions: opt, Logger: log.StandardLogger()})
+	return err
 }
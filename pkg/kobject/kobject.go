@@ -38,7 +38,20 @@ type KomposeObject struct {
 	// as they can have different names. For example environment variables  are called environment in compose but Env in bundle.
 	LoadedFrom string
 
+	// Name is the project name, given by the top-level name of Compose Specification files
+	Name string
+
 	Secrets map[string]dockerCliTypes.SecretConfig
+	// SecretsContent holds the content of the secrets created from an environment variable, keyed by secret name
+	SecretsContent map[string]string
+	// ConfigsContent holds the content of the configs defined inline, keyed by config name
+	ConfigsContent map[string]string
+
+	// NamedVolumes are the top-level volumes, keyed by their normalized name
+	NamedVolumes map[string]NamedVolume
+
+	// Report collects the fields dropped, approximated or renamed by the loader and the transformer
+	Report *Report
 }
 
 // ConvertOptions holds all options that controls transformation process
@@ -79,6 +92,36 @@ type ConvertOptions struct {
 	WithKomposeAnnotation bool
 
 	MultipleContainerMode bool
+
+	Profiles []string
+
+	WaitForDependencies bool
+	// WaitForDependenciesImage is the image of the init containers waiting for the dependencies,
+	// it needs sh and nc
+	WaitForDependenciesImage string
+
+	EnvFiles []string
+
+	// Report is the file the conversion report is written to, in JSON if it ends with .json and YAML otherwise
+	Report string
+
+	// Format is the layout of the files written, "kustomize" writes a Kustomize base and its overlays
+	Format string
+
+	// Overlays are the compose files overriding the input files of each Kustomize overlay, by overlay name
+	Overlays map[string]string
+
+	// CreateNamespace adds the Namespace, or OpenShift Project, the objects are created in
+	CreateNamespace bool
+
+	// KubeVersion is the Kubernetes version the objects are generated for, like 1.21, empty for the latest one
+	KubeVersion string
+
+	// CreatePDB adds a PodDisruptionBudget for the Deployments and StatefulSets with more than one replica
+	CreatePDB bool
+
+	// GPUResource is the extended resource requested for the GPUs reserved by the services, like nvidia.com/gpu
+	GPUResource string
 }
 
 // IsPodController indicate if the user want to use a controller
@@ -129,6 +172,7 @@ type ServiceConfig struct {
 	BuildLabels       map[string]string   `compose:"build-labels"`
 	ExposeServiceTLS  string              `compose:"kompose.service.expose.tls-secret"`
 	ImagePullSecret   string              `compose:"kompose.image-pull-secret"`
+	CronJobSchedule   string              `compose:"kompose.cronjob.schedule"`
 	Stdin             bool                `compose:"stdin_open"`
 	Tty               bool                `compose:"tty"`
 	MemLimit          yaml.MemStringorInt `compose:"mem_limit"`
@@ -137,10 +181,13 @@ type ServiceConfig struct {
 	// DeployLabels mapping to kubernetes labels
 	DeployLabels       map[string]string           `compose:""`
 	DeployUpdateConfig dockerCliTypes.UpdateConfig `compose:""`
+	RestartMaxAttempts *uint64                     `compose:""`
 	TmpFs              []string                    `compose:"tmpfs"`
 	Dockerfile         string                      `compose:"dockerfile"`
 	Replicas           int                         `compose:"replicas"`
 	GroupAdd           []int64                     `compose:"group_add"`
+	Profiles           []string                    `compose:"profiles"`
+	DependsOn          map[string]string           `compose:"depends_on"`
 	Volumes            []Volumes                   `compose:""`
 	Secrets            []dockerCliTypes.ServiceSecretConfig
 	HealthChecks       HealthChecks      `compose:""`
@@ -149,8 +196,47 @@ type ServiceConfig struct {
 	Configs []dockerCliTypes.ServiceConfigObjConfig `compose:""`
 	//This is for SHORT SYNTAX link(https://docs.docker.com/compose/compose-file/#configs)
 	ConfigsMetaData map[string]dockerCliTypes.ConfigObjConfig `compose:""`
+	SecretsMetaData map[string]dockerCliTypes.SecretConfig    `compose:""`
 
 	WithKomposeAnnotation bool `compose:""`
+
+	// ExposeServiceIngressClassName is the IngressClass of the ingress resource
+	ExposeServiceIngressClassName string `compose:"kompose.service.expose.ingress-class-name"`
+	// ExposeServiceAnnotations are the annotations of the ingress resource
+	ExposeServiceAnnotations map[string]string `compose:""`
+
+	// HPAMinReplicas, HPAMaxReplicas, HPACPU and HPAMemory configure the HorizontalPodAutoscaler of the service,
+	// HPACPU and HPAMemory are the target utilization percentages of the resource requests
+	HPAMinReplicas int32 `compose:"kompose.hpa.min"`
+	HPAMaxReplicas int32 `compose:"kompose.hpa.max"`
+	HPACPU         int32 `compose:"kompose.hpa.cpu"`
+	HPAMemory      int32 `compose:"kompose.hpa.memory"`
+
+	// PDBMinAvailable is the minAvailable of the PodDisruptionBudget of the service, a number or a percentage
+	PDBMinAvailable string `compose:"kompose.pdb.min-available"`
+
+	// DNS, DNSSearch and DNSOpts are the nameservers, search domains and resolver options of the pods,
+	// ExtraHosts are the "host:ip" entries added to their /etc/hosts
+	DNS        []string `compose:"dns"`
+	DNSSearch  []string `compose:"dns_search"`
+	DNSOpts    []string `compose:"dns_opt"`
+	ExtraHosts []string `compose:"extra_hosts"`
+
+	// Sysctls are the namespaced kernel parameters of the pods, ShmSize is the size of /dev/shm in bytes
+	// and ReadOnly mounts the root filesystem of the container as read-only
+	Sysctls  map[string]string `compose:"sysctls"`
+	ShmSize  int64             `compose:"shm_size"`
+	ReadOnly bool              `compose:"read_only"`
+
+	// SecurityOpt are the seccomp, apparmor, label and no-new-privileges options of the container
+	SecurityOpt []string `compose:"security_opt"`
+
+	// Devices are the "host[:container[:permissions]]" devices of the container, DevicesResource is the
+	// device plugin resource requested instead of mounting them, and GPUs is the number of GPUs reserved
+	// by deploy.resources.reservations.devices
+	Devices         []string `compose:"devices"`
+	DevicesResource string   `compose:"kompose.devices.resource"`
+	GPUs            int64    `compose:""`
 }
 
 // HealthChecks used to distinguish between liveness and readiness
@@ -199,10 +285,34 @@ type Volumes struct {
 	PVCName       string // name of PVC
 	PVCSize       string // PVC size
 	SelectorValue string // Value of the label selector
+	External      bool   // volume already exists in the cluster, no PVC is created
+	ClaimName     string // name of the existing PVC referenced by an external volume
+	StorageClass  string // storageClassName of the PVC
+	VolumeMode    string // volumeMode of the PVC (Filesystem or Block)
+	AccessMode    string // access mode of the PVC, overriding the one derived from Mode
+	NFSServer     string // NFS server of a volume using the nfs driver_opts
+	NFSPath       string // exported path of a volume using the nfs driver_opts
+	Shared        bool   // the named volume is mounted by several services, its PVC is created once from NamedVolumes
+}
+
+// NamedVolume holds the configuration of a top-level volume and the services mounting it
+type NamedVolume struct {
+	Name          string   // normalized name of the volume, also the name of its PVC
+	PVCSize       string   // PVC size
+	SelectorValue string   // Value of the label selector
+	External      bool     // volume already exists in the cluster, no PVC is created
+	ClaimName     string   // name of the existing PVC referenced by an external volume
+	StorageClass  string   // storageClassName of the PVC
+	VolumeMode    string   // volumeMode of the PVC (Filesystem or Block)
+	AccessMode    string   // access mode of the PVC, computed from all the consumers unless set by label
+	NFSServer     string   // NFS server of a volume using the nfs driver_opts
+	NFSPath       string   // exported path of a volume using the nfs driver_opts
+	Consumers     []string // sorted names of the services mounting the volume
 }
 
 // GetConfigMapKeyFromMeta ...
 // given a source name ,find the file and extract the filename which will be act as ConfigMap key
+// the key of a config defined inline, which has no file, is its name
 // return "" if not found
 func (s *ServiceConfig) GetConfigMapKeyFromMeta(name string) (string, error) {
 	if s.ConfigsMetaData == nil {
@@ -216,6 +326,9 @@ func (s *ServiceConfig) GetConfigMapKeyFromMeta(name string) (string, error) {
 	if config.External.External {
 		return "", errors.Errorf("config %s is external", name)
 	}
+	if config.File == "" {
+		return name, nil
+	}
 
 	return filepath.Base(config.File), nil
 }
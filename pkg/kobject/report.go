@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kobject
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ReportDropped is the action of a field which is ignored by the conversion
+	ReportDropped = "dropped"
+	// ReportApproximated is the action of a field which is converted to the closest supported value
+	ReportApproximated = "approximated"
+	// ReportRenamed is the action of a name which is changed to be a valid Kubernetes name
+	ReportRenamed = "renamed"
+)
+
+// ReportEntry is a field of the compose files which isn't converted as is
+type ReportEntry struct {
+	// Service is the name of the service in the compose files, empty for the top-level fields
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// Field is the path of the field, like restart or deploy.placement.constraints,
+	// empty when the service itself is renamed
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+	// File and Line locate the field, they are empty when it can't be found in the compose files
+	File   string `json:"file,omitempty" yaml:"file,omitempty"`
+	Line   int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Action string `json:"action" yaml:"action"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// Warning is a warning raised while loading or converting the compose files
+type Warning struct {
+	// Service is the name of the service the warning is about, empty for the warnings about the whole project
+	Service string
+	// Field is the path of the field the warning is about, like security_opt or deploy.replicas,
+	// empty when the warning is about the service itself
+	Field string
+	// File and Line locate the field, they are empty when it can't be found in the compose files
+	File    string
+	Line    int
+	Message string
+}
+
+// Report lists the fields of the compose files which are dropped, approximated or renamed during a conversion
+type Report struct {
+	Entries []ReportEntry `json:"entries" yaml:"entries"`
+
+	// Warnings are the warnings raised during the conversion, they aren't written with the entries
+	Warnings []Warning `json:"-" yaml:"-"`
+	// Logger prints the warnings as they are raised, they are only recorded when it is nil
+	Logger log.FieldLogger `json:"-" yaml:"-"`
+
+	// locate returns the file and the line of a field of a service
+	locate func(service string, field string) (string, int)
+}
+
+// NewReport creates a report locating its entries with the given function
+func NewReport(locate func(service string, field string) (string, int)) *Report {
+	return &Report{locate: locate}
+}
+
+// Add adds an entry to the report, the entry is located in the compose files unless its file is set.
+// Nothing is recorded on a nil report.
+func (r *Report) Add(entry ReportEntry) {
+	if r == nil {
+		return
+	}
+	if entry.File == "" && r.locate != nil {
+		entry.File, entry.Line = r.locate(entry.Service, entry.Field)
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// Warn records a warning about a field of a service and prints it with the logger of the report.
+// The warning is printed with the standard logger on a nil report.
+func (r *Report) Warn(service string, field string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if r == nil {
+		log.Warn(message)
+		return
+	}
+	warning := Warning{Service: service, Field: field, Message: message}
+	if r.locate != nil {
+		warning.File, warning.Line = r.locate(service, field)
+	}
+	r.Warnings = append(r.Warnings, warning)
+	if r.Logger != nil {
+		r.Logger.Warn(message)
+	}
+}
+
+// Sort sorts the entries by file, line, service and field
+func (r *Report) Sort() {
+	if r == nil {
+		return
+	}
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		a, b := r.Entries[i], r.Entries[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Field < b.Field
+	})
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kompose converts Docker Compose files to Kubernetes and OpenShift objects.
+// It is the library behind the kompose command line, it never exits the process.
+package kompose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/kubernetes/kompose/pkg/kobject"
+	"github.com/kubernetes/kompose/pkg/loader/compose"
+	"github.com/kubernetes/kompose/pkg/transformer"
+	"github.com/kubernetes/kompose/pkg/transformer/kubernetes"
+	"github.com/kubernetes/kompose/pkg/transformer/openshift"
+)
+
+const (
+	// ProviderKubernetes is provider kubernetes
+	ProviderKubernetes = "kubernetes"
+	// ProviderOpenshift is provider openshift
+	ProviderOpenshift = "openshift"
+	// DefaultProvider - provider that will be used if there is no provider was explicitly set
+	DefaultProvider = ProviderKubernetes
+)
+
+// Options are the options of a conversion
+type Options struct {
+	kobject.ConvertOptions
+
+	// Stdin is read when an input file is "-", os.Stdin is used when nil
+	Stdin io.Reader
+
+	// SkipOutput returns the objects without writing them to files or stdout
+	SkipOutput bool
+
+	// Logger prints the warnings as they are raised, they are only returned in the result when it is nil
+	Logger log.FieldLogger
+}
+
+// Warning is a warning raised during a conversion
+type Warning = kobject.Warning
+
+// Result is the result of a conversion
+type Result struct {
+	// Objects are the converted objects
+	Objects []runtime.Object
+	// Warnings are the warnings raised while loading and converting the files
+	Warnings []Warning
+	// Overlays are the converted objects of each Kustomize overlay
+	Overlays map[string][]runtime.Object
+	// Report lists the fields dropped, approximated or renamed during the conversion
+	Report []kobject.ReportEntry
+	// Files are the paths of the files written
+	Files []string
+}
+
+// Convert loads the compose files of the options and converts them to Kubernetes or OpenShift objects.
+// The objects are written according to the options unless SkipOutput is set.
+func Convert(ctx context.Context, opts Options) (Result, error) {
+	opt := opts.ConvertOptions
+	setDefaults(&opt)
+	if err := ValidateOptions(&opt); err != nil {
+		return Result{}, err
+	}
+	if err := validateControllers(&opt); err != nil {
+		return Result{}, err
+	}
+
+	// the input files are loaded again for every overlay, stdin can only be read once
+	stdin := opts.Stdin
+	if len(opt.Overlays) > 0 {
+		var err error
+		if stdin, err = bufferStdin(opt.InputFiles, stdin); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// the warnings raised before an error are returned with it
+	komposeObject, objects, err := loadAndTransform(ctx, opt.InputFiles, stdin, opts.Logger, opt)
+	result := Result{Objects: objects}
+	if komposeObject.Report != nil {
+		komposeObject.Report.Sort()
+		result.Warnings = komposeObject.Report.Warnings
+		result.Report = komposeObject.Report.Entries
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if len(opt.Overlays) > 0 {
+		result.Overlays = make(map[string][]runtime.Object, len(opt.Overlays))
+	}
+	for name, file := range opt.Overlays {
+		if seeker, ok := stdin.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return result, err
+			}
+		}
+		files := append(append([]string{}, opt.InputFiles...), file)
+		overlayObject, overlayObjects, err := loadAndTransform(ctx, files, stdin, opts.Logger, opt)
+		if overlayObject.Report != nil {
+			result.Warnings = append(result.Warnings, overlayObject.Report.Warnings...)
+		}
+		if err != nil {
+			return result, errors.Wrapf(err, "unable to convert overlay %s", name)
+		}
+		result.Overlays[name] = overlayObjects
+	}
+
+	if opt.Report != "" {
+		if err := writeReport(komposeObject.Report, opt); err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, opt.Report)
+	}
+
+	if opts.SkipOutput {
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// Print output
+	var files []string
+	if opt.Format == kubernetes.FormatKustomize {
+		files, err = kubernetes.PrintKustomize(objects, result.Overlays, opt)
+	} else {
+		files, err = kubernetes.PrintList(objects, opt)
+	}
+	result.Files = append(result.Files, files...)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// writeReport writes the conversion report to the report file of the options,
+// in JSON if its name ends with .json and in YAML otherwise
+func writeReport(report *kobject.Report, opt kobject.ConvertOptions) error {
+	if report.Entries == nil {
+		report.Entries = []kobject.ReportEntry{}
+	}
+
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(opt.Report), ".json") {
+		data, err = json.MarshalIndent(report, "", strings.Repeat(" ", opt.YAMLIndent))
+		data = append(data, '\n')
+	} else {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(opt.YAMLIndent)
+		err = encoder.Encode(report)
+		data = buf.Bytes()
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the conversion report")
+	}
+
+	if err := ioutil.WriteFile(opt.Report, data, 0644); err != nil {
+		return errors.Wrapf(err, "unable to write the conversion report to %s", opt.Report)
+	}
+	log.Printf("Conversion report %q created", opt.Report)
+	return nil
+}
+
+// loadAndTransform loads compose files and converts them with the transformer of the provider,
+// the warnings are recorded in the report of the returned object and printed with the logger unless it is nil,
+// the object is returned with an error too so that the warnings raised before it aren't lost
+func loadAndTransform(ctx context.Context, files []string, stdin io.Reader, logger log.FieldLogger, opt kobject.ConvertOptions) (kobject.KomposeObject, []runtime.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return kobject.KomposeObject{}, nil, err
+	}
+
+	// loader parses input from file into komposeObject.
+	l := &compose.Compose{Stdin: stdin, Logger: logger}
+	komposeObject, err := l.LoadFile(files, opt.Profiles, opt.EnvFiles)
+	if err != nil {
+		return komposeObject, nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return komposeObject, nil, err
+	}
+
+	// Get a transformer that maps komposeObject to provider's primitives
+	t := getTransformer(opt)
+
+	// the transformer adds its own entries to the report of the loader
+	if komposeObject.Report == nil {
+		komposeObject.Report = kobject.NewReport(nil)
+		komposeObject.Report.Logger = logger
+	}
+
+	// Do the transformation
+	objects, err := t.Transform(komposeObject, opt)
+	if err != nil {
+		return komposeObject, nil, err
+	}
+	return komposeObject, objects, nil
+}
+
+// bufferStdin reads stdin once if one of the files is "-", so that it can be read again
+func bufferStdin(files []string, stdin io.Reader) (io.Reader, error) {
+	for _, file := range files {
+		if file != "-" {
+			continue
+		}
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read stdin")
+		}
+		return bytes.NewReader(data), nil
+	}
+	return stdin, nil
+}
+
+// setDefaults sets the options left empty to the defaults of the command line
+func setDefaults(opt *kobject.ConvertOptions) {
+	if opt.Provider == "" {
+		opt.Provider = DefaultProvider
+	}
+	if opt.Volumes == "" {
+		opt.Volumes = "persistentVolumeClaim"
+	}
+	if opt.Replicas == 0 && !opt.IsReplicaSetFlag {
+		opt.Replicas = 1
+	}
+	if opt.YAMLIndent == 0 {
+		opt.YAMLIndent = 2
+	}
+}
+
+// ValidateOptions validates the options which don't depend on the command line
+func ValidateOptions(opt *kobject.ConvertOptions) error {
+	if len(opt.InputFiles) == 0 {
+		return errors.New("No compose file given")
+	}
+
+	if len(opt.OutFile) != 0 && opt.ToStdout {
+		return errors.New("Error: --out and --stdout can't be set at the same time")
+	}
+
+	if opt.CreateChart && opt.ToStdout {
+		return errors.New("Error: chart cannot be generated when --stdout is specified")
+	}
+
+	if opt.Replicas < 0 {
+		return errors.New("Error: --replicas cannot be negative")
+	}
+
+	if opt.GenerateJSON && opt.GenerateYaml {
+		return errors.New("YAML and JSON format cannot be provided at the same time")
+	}
+
+	if opt.Volumes != "persistentVolumeClaim" && opt.Volumes != "emptyDir" && opt.Volumes != "hostPath" && opt.Volumes != "configMap" {
+		return errors.Errorf("Unknown Volume type: %s, possible values are: persistentVolumeClaim, configMap and emptyDir", opt.Volumes)
+	}
+
+	if opt.Format != "" && opt.Format != kubernetes.FormatKustomize {
+		return errors.Errorf("Unknown format: %s, possible values are: kustomize", opt.Format)
+	}
+
+	if opt.Format == kubernetes.FormatKustomize {
+		if opt.ToStdout || opt.CreateChart {
+			return errors.New("Error: --format kustomize writes a directory, it can't be used with --stdout or --chart")
+		}
+		if info, err := os.Stat(opt.OutFile); err == nil && !info.IsDir() {
+			return errors.Errorf("Error: --format kustomize writes a directory but %s is a file", opt.OutFile)
+		}
+	}
+
+	if len(opt.Overlays) > 0 && opt.Format != kubernetes.FormatKustomize {
+		return errors.New("Error: --overlay can only be used with --format kustomize")
+	}
+	for name, file := range opt.Overlays {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return errors.Errorf("Error: invalid overlay name %q", name)
+		}
+		if file == "" {
+			return errors.Errorf("Error: overlay %s has no compose file", name)
+		}
+	}
+
+	if opt.KubeVersion != "" {
+		if _, err := kubernetes.ParseKubeVersion(opt.KubeVersion); err != nil {
+			return errors.Wrap(err, "Error")
+		}
+	}
+
+	if opt.GPUResource != "" {
+		if errs := validation.IsQualifiedName(opt.GPUResource); len(errs) > 0 || !strings.Contains(opt.GPUResource, "/") {
+			return errors.Errorf("Error: invalid GPU resource %q, expected a resource name like vendor.com/gpu", opt.GPUResource)
+		}
+	}
+
+	if opt.Namespace != "" {
+		if errs := validation.IsDNS1123Label(opt.Namespace); len(errs) > 0 {
+			return errors.Errorf("Error: invalid namespace %q: %s", opt.Namespace, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+func validateControllers(opt *kobject.ConvertOptions) error {
+	singleOutput := len(opt.OutFile) != 0 || opt.OutFile == "-" || opt.ToStdout
+	if opt.Provider == ProviderKubernetes {
+		// create deployment by default if no controller has been set
+		if !opt.CreateD && !opt.CreateDS && !opt.CreateRC && opt.Controller == "" {
+			opt.CreateD = true
+		}
+		if singleOutput {
+			count := 0
+			if opt.CreateD {
+				count++
+			}
+			if opt.CreateDS {
+				count++
+			}
+			if opt.CreateRC {
+				count++
+			}
+			if count > 1 {
+				return errors.New("Error: only one kind of Kubernetes resource can be generated when --out or --stdout is specified")
+			}
+		}
+	} else if opt.Provider == ProviderOpenshift {
+		// create deploymentconfig by default if no controller has been set
+		if !opt.CreateDeploymentConfig {
+			opt.CreateDeploymentConfig = true
+		}
+		if singleOutput {
+			count := 0
+			if opt.CreateDeploymentConfig {
+				count++
+			}
+			// Add more controllers here once they are available in OpenShift
+			// if opt.foo {count++}
+
+			if count > 1 {
+				return errors.New("Error: only one kind of OpenShift resource can be generated when --out or --stdout is specified")
+			}
+		}
+	}
+	return nil
+}
+
+// Convenience method to return the appropriate Transformer based on
+// what provider we are using.
+func getTransformer(opt kobject.ConvertOptions) transformer.Transformer {
+	var t transformer.Transformer
+	if opt.Provider == ProviderOpenshift {
+		// Create/Init new OpenShift object that is initialized with a newly
+		// created Kubernetes object. Openshift inherits from Kubernetes
+		t = &openshift.OpenShift{Kubernetes: kubernetes.Kubernetes{Opt: opt}}
+	} else {
+		// Create/Init new Kubernetes object with CLI opts
+		t = &kubernetes.Kubernetes{Opt: opt}
+	}
+	return t
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kompose
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes/kompose/pkg/kobject"
+	appsv1 "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+)
+
+func TestConvert(t *testing.T) {
+	compose := `version: "3"
+services:
+  web:
+    image: nginx
+    ports:
+      - "80:80"
+  worker:
+    image: busybox
+`
+
+	result, err := Convert(context.Background(), Options{
+		ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}},
+		Stdin:          strings.NewReader(compose),
+		SkipOutput:     true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var deployments, services int
+	for _, object := range result.Objects {
+		switch object.(type) {
+		case *appsv1.Deployment:
+			deployments++
+		case *api.Service:
+			services++
+		}
+	}
+	if deployments != 2 || services != 1 {
+		t.Errorf("Expected 2 deployments and 1 service, got %d and %d", deployments, services)
+	}
+
+	found := false
+	for _, warning := range result.Warnings {
+		if warning.Service == "worker" && warning.Field == "ports" && strings.Contains(warning.Message, `Service "worker" won't be created`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a warning about the service of worker, got %v", result.Warnings)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected no file to be written, got %v", result.Files)
+	}
+}
+
+func TestConvertWritesFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "k8s.yaml")
+	result, err := Convert(context.Background(), Options{
+		ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, OutFile: out},
+		Stdin:          strings.NewReader("version: \"3\"\nservices:\n  web:\n    image: nginx\n"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0] != out {
+		t.Errorf("Expected %s to be written, got %v", out, result.Files)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("Expected %s to exist: %v", out, err)
+	}
+}
+
+func TestConvertReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report := filepath.Join(dir, "report.json")
+	result, err := Convert(context.Background(), Options{
+		ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, Report: report},
+		Stdin:          strings.NewReader("version: \"3\"\nservices:\n  web:\n    image: nginx\n    restart: unless-stopped\n"),
+		SkipOutput:     true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0] != report {
+		t.Errorf("Expected %s to be written, got %v", report, result.Files)
+	}
+
+	expected := []kobject.ReportEntry{
+		{Service: "web", Field: "restart", File: "-", Line: 5, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+	}
+	if !reflect.DeepEqual(result.Report, expected) {
+		t.Errorf("Expected report %+v, got %+v", expected, result.Report)
+	}
+
+	data, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written kobject.Report
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("Unable to unmarshal the report: %v", err)
+	}
+	if !reflect.DeepEqual(written.Entries, expected) {
+		t.Errorf("Expected written report %+v, got %+v", expected, written.Entries)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := map[string]struct {
+		ctx   context.Context
+		opts  Options
+		error string
+	}{
+		"No input file": {context.Background(), Options{}, "No compose file given"},
+		"Missing file": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"missing.yml"}},
+		}, "no such file"},
+		"Missing secret file": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}},
+			Stdin:          strings.NewReader("version: \"3.1\"\nservices:\n  web:\n    image: nginx\n    secrets:\n      - token\nsecrets:\n  token:\n    file: /missing/token\n"),
+			SkipOutput:     true,
+		}, "unable to read secret from file /missing/token"},
+		"Overlay without kustomize": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, Overlays: map[string]string{"prod": "prod.yml"}},
+		}, "--overlay can only be used with --format kustomize"},
+		"Kind not served by the Kubernetes version": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, KubeVersion: "1.7"},
+			Stdin:          strings.NewReader("version: \"3\"\nservices:\n  backup:\n    image: busybox\n    labels:\n      kompose.cronjob.schedule: \"0 * * * *\"\n"),
+			SkipOutput:     true,
+		}, "CronJob can't be generated for Kubernetes 1.7"},
+		"Invalid Kubernetes version": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, KubeVersion: "latest"},
+		}, "invalid Kubernetes version"},
+		"Invalid GPU resource": {context.Background(), Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, GPUResource: "gpu"},
+		}, "invalid GPU resource"},
+		"Canceled context": {ctx, Options{
+			ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}},
+			Stdin:          strings.NewReader("version: \"3\"\nservices:\n  web:\n    image: nginx\n"),
+		}, "context canceled"},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		_, err := Convert(test.ctx, test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Errorf("Expected error containing %q, got %v", test.error, err)
+		}
+	}
+}
+
+func TestConvertErrorWarnings(t *testing.T) {
+	result, err := Convert(context.Background(), Options{
+		ConvertOptions: kobject.ConvertOptions{InputFiles: []string{"-"}, KubeVersion: "1.7"},
+		Stdin:          strings.NewReader("version: \"3\"\nservices:\n  backup:\n    image: busybox\n    restart: unless-stopped\n    labels:\n      kompose.cronjob.schedule: \"0 * * * *\"\n"),
+		SkipOutput:     true,
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	expected := []kobject.ReportEntry{
+		{Service: "backup", Field: "restart", File: "-", Line: 5, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+	}
+	if !reflect.DeepEqual(result.Report, expected) {
+		t.Errorf("Expected report %+v, got %+v", expected, result.Report)
+	}
+	if len(result.Warnings) == 0 || result.Warnings[0].Field != "restart" {
+		t.Errorf("Expected the warnings raised before the error, got %+v", result.Warnings)
+	}
+}
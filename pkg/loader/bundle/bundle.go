@@ -174,8 +174,8 @@ func loadPorts(service Service) ([]kobject.Ports, error) {
 	return ports, nil
 }
 
-// LoadFile loads dab file into KomposeObject
-func (b *Bundle) LoadFile(files []string) (kobject.KomposeObject, error) {
+// LoadFile loads dab file into KomposeObject, bundles have no profiles nor variables
+func (b *Bundle) LoadFile(files []string, profiles []string, envFiles []string) (kobject.KomposeObject, error) {
 	komposeObject := kobject.KomposeObject{
 		ServiceConfigs: make(map[string]kobject.ServiceConfig),
 		LoadedFrom:     "bundle",
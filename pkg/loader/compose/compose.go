@@ -18,7 +18,9 @@ package compose
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -30,18 +32,20 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-//StdinData is data bytes read from stdin
-var StdinData []byte
-
 // Compose is docker compose file loader, implements Loader interface
 type Compose struct {
+	// Stdin is read when the file is "-", os.Stdin is used when nil
+	Stdin io.Reader
+	// Logger prints the warnings of the loader, they are only recorded in the report when it is nil
+	Logger log.FieldLogger
 }
 
 // checkUnsupportedKey checks if libcompose project contains
 // keys that are not supported by this loader.
 // list of all unsupported keys are stored in unsupportedKey variable
-// returns list of unsupported YAML keys from docker-compose
-func checkUnsupportedKey(composeProject *project.Project) []string {
+// returns list of unsupported YAML keys from docker-compose,
+// every service using one of them is added to the report
+func checkUnsupportedKey(composeProject *project.Project, report *kobject.Report) []string {
 	// list of all unsupported keys for this loader
 	// this is map to make searching for keys easier
 	// to make sure that unsupported key is not going to be reported twice
@@ -50,27 +54,18 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 		"CgroupParent":  false,
 		"CPUSet":        false,
 		"CPUShares":     false,
-		"Devices":       false,
-		"DependsOn":     false,
-		"DNS":           false,
-		"DNSSearch":     false,
 		"EnvFile":       false,
 		"ExternalLinks": false,
-		"ExtraHosts":    false,
 		"Ipc":           false,
 		"Logging":       false,
 		"MacAddress":    false,
 		"MemSwapLimit":  false,
 		"NetworkMode":   false,
-		"SecurityOpt":   false,
-		"ShmSize":       false,
 		"StopSignal":    false,
 		"VolumeDriver":  false,
 		"Uts":           false,
-		"ReadOnly":      false,
 		"Ulimits":       false,
 		"Net":           false,
-		"Sysctls":       false,
 		//"Networks":    false, // We shall be spporting network now. There are special checks for Network in checkUnsupportedKey function
 		"Links": false,
 	}
@@ -83,19 +78,31 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 		log.Debug("Default network found")
 	}
 
-	// Root level volumes are not yet supported
-	if len(composeProject.VolumeConfigs) > 0 {
-		keysFound = append(keysFound, "root level volumes")
+	// Root level volumes are converted to claims, only the local driver is supported
+	var volumeNames []string
+	for name := range composeProject.VolumeConfigs {
+		volumeNames = append(volumeNames, name)
+	}
+	sort.Strings(volumeNames)
+	for _, name := range volumeNames {
+		volume := composeProject.VolumeConfigs[name]
+		if volume == nil || volume.Driver == "" || volume.Driver == "local" {
+			continue
+		}
+		if len(keysFound) == 0 {
+			keysFound = append(keysFound, "root level volumes")
+		}
+		report.Add(kobject.ReportEntry{Field: "volumes", Action: kobject.ReportDropped, Reason: fmt.Sprintf("driver %s of root level volume %s is not supported", volume.Driver, name)})
 	}
 
-	for _, serviceConfig := range composeProject.ServiceConfigs.All() {
+	for name, serviceConfig := range composeProject.ServiceConfigs.All() {
 		// this reflection is used in check for empty arrays
 		val := reflect.ValueOf(serviceConfig).Elem()
 		s := structs.New(serviceConfig)
 
 		for _, f := range s.Fields() {
 			// Check if given key is among unsupported keys, and skip it if we already saw this key
-			if alreadySaw, ok := unsupportedKey[f.Name()]; ok && !alreadySaw {
+			if alreadySaw, ok := unsupportedKey[f.Name()]; ok {
 				if f.IsExported() && !f.IsZero() {
 					// IsZero returns false for empty array/slice ([])
 					// this check if field is Slice, and then it checks its size
@@ -133,8 +140,16 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 						}
 					}
 
-					keysFound = append(keysFound, yamlTagName)
-					unsupportedKey[f.Name()] = true
+					report.Add(kobject.ReportEntry{
+						Service: name,
+						Field:   yamlTagName,
+						Action:  kobject.ReportDropped,
+						Reason:  fmt.Sprintf("%s key is not supported", yamlTagName),
+					})
+					if !alreadySaw {
+						keysFound = append(keysFound, yamlTagName)
+						unsupportedKey[f.Name()] = true
+					}
 				}
 			}
 		}
@@ -143,12 +158,20 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 }
 
 // LoadFile loads a compose file into KomposeObject
-func (c *Compose) LoadFile(files []string) (kobject.KomposeObject, error) {
+// only the services enabled by the given profiles are loaded
+// and the variables are interpolated from the given env files (default is the .env file of the project directory),
+// when the files can't be parsed the object returned with the error only holds the report of the warnings raised so far
+func (c *Compose) LoadFile(files []string, profiles []string, envFiles []string) (kobject.KomposeObject, error) {
 	// Load the json / yaml file in order to get the version value
 	var version string
 
+	contents, err := readComposeFiles(files, c.Stdin)
+	if err != nil {
+		return kobject.KomposeObject{}, errors.Wrap(err, "Unable to read compose file")
+	}
+
 	for _, file := range files {
-		composeVersion, err := getVersionFromFile(file)
+		composeVersion, err := getVersionFromFile(contents[file])
 		if err != nil {
 			return kobject.KomposeObject{}, errors.Wrap(err, "Unable to load yaml/json file for version parsing")
 		}
@@ -162,21 +185,42 @@ func (c *Compose) LoadFile(files []string) (kobject.KomposeObject, error) {
 
 	log.Debugf("Docker Compose version: %s", version)
 
+	// the fields which aren't converted as is are located in the original files
+	report := newReport(files, contents)
+	report.Logger = c.Logger
+
 	// Convert based on version
 	switch version {
+	// If blank, it's either the Compose Specification or version 1
+	case "":
+		spec, err := isComposeSpec(files, contents)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrap(err, "Unable to load yaml/json file for Compose Specification detection")
+		}
+		if spec {
+			komposeObject, err := parseComposeSpec(files, contents, profiles, envFiles, report)
+			if err != nil {
+				return kobject.KomposeObject{Report: report}, err
+			}
+			return komposeObject, nil
+		}
+		komposeObject, err := parseV1V2(files, contents, envFiles, report)
+		if err != nil {
+			return kobject.KomposeObject{Report: report}, err
+		}
+		return komposeObject, nil
 	// Use libcompose for 1 or 2
-	// If blank, it's assumed it's 1 or 2
-	case "", "1", "1.0", "2", "2.0", "2.1", "2.2":
-		komposeObject, err := parseV1V2(files)
+	case "1", "1.0", "2", "2.0", "2.1", "2.2":
+		komposeObject, err := parseV1V2(files, contents, envFiles, report)
 		if err != nil {
-			return kobject.KomposeObject{}, err
+			return kobject.KomposeObject{Report: report}, err
 		}
 		return komposeObject, nil
 		// Use docker/cli for 3
 	case "3", "3.0", "3.1", "3.2", "3.3", "3.4", "3.5", "3.6", "3.7", "3.8":
-		komposeObject, err := parseV3(files)
+		komposeObject, err := parseV3(files, contents, envFiles, report)
 		if err != nil {
-			return kobject.KomposeObject{}, err
+			return kobject.KomposeObject{Report: report}, err
 		}
 		return komposeObject, nil
 	default:
@@ -184,18 +228,27 @@ func (c *Compose) LoadFile(files []string) (kobject.KomposeObject, error) {
 	}
 }
 
-func getVersionFromFile(file string) (string, error) {
+// isComposeSpec checks if all the versionless files follow the Compose Specification
+func isComposeSpec(files []string, contents composeFiles) (bool, error) {
+	for _, file := range files {
+		spec, err := isComposeSpecFile(contents[file])
+		if err != nil {
+			return false, err
+		}
+		if !spec {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func getVersionFromFile(loadedFile []byte) (string, error) {
 	type ComposeVersion struct {
 		Version string `json:"version"` // This affects YAML as well
 	}
 	var version ComposeVersion
-	loadedFile, err := ReadFile(file)
-
-	if err != nil {
-		return "", err
-	}
 
-	err = yaml.Unmarshal(loadedFile, &version)
+	err := yaml.Unmarshal(loadedFile, &version)
 	if err != nil {
 		return "", err
 	}
@@ -18,7 +18,9 @@ package compose
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -115,6 +117,133 @@ func TestLoadV3Volumes(t *testing.T) {
 	}
 }
 
+func TestHandleV3Volume(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web":    {Name: "web", VolList: []string{"shared_data:/data", "legacy:/legacy", "exports:/exports:ro", "assets:/assets:ro"}},
+			"worker": {Name: "worker", VolList: []string{"shared_data:/data", "fast:/fast", "assets:/assets:ro"}},
+		},
+	}
+	volumes := map[string]types.VolumeConfig{
+		"shared_data": {Labels: types.Labels{LabelVolumeStorageClassName: "efs"}},
+		"fast":        {Labels: types.Labels{LabelVolumeMode: "Block", LabelVolumeAccessMode: "ReadWriteOnce", LabelVolumeSize: "5Gi"}},
+		"legacy":      {Name: "legacy-claim", External: types.External{External: true}},
+		"exports":     {DriverOpts: map[string]string{"type": "nfs", "o": "addr=10.0.0.5,nfsvers=4", "device": ":/exports/data"}},
+		"assets":      {},
+	}
+
+	if err := handleV3Volume(&komposeObject, &volumes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]kobject.Volumes{
+		"web/shared-data":    {StorageClass: "efs", AccessMode: "ReadWriteMany", Shared: true},
+		"worker/shared-data": {StorageClass: "efs", AccessMode: "ReadWriteMany", Shared: true},
+		"web/assets":         {AccessMode: "ReadOnlyMany", Shared: true},
+		"worker/assets":      {AccessMode: "ReadOnlyMany", Shared: true},
+		"worker/fast":        {PVCSize: "5Gi", VolumeMode: "Block", AccessMode: "ReadWriteOnce"},
+		"web/legacy":         {External: true, ClaimName: "legacy-claim"},
+		"web/exports":        {NFSServer: "10.0.0.5", NFSPath: "/exports/data"},
+	}
+	for name, service := range komposeObject.ServiceConfigs {
+		for _, vol := range service.Volumes {
+			want := expected[name+"/"+vol.VolumeName]
+			got := kobject.Volumes{
+				PVCSize:      vol.PVCSize,
+				External:     vol.External,
+				ClaimName:    vol.ClaimName,
+				StorageClass: vol.StorageClass,
+				VolumeMode:   vol.VolumeMode,
+				AccessMode:   vol.AccessMode,
+				NFSServer:    vol.NFSServer,
+				NFSPath:      vol.NFSPath,
+				Shared:       vol.Shared,
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("Volume %s of %s: expected %+v, got %+v", vol.VolumeName, name, want, got)
+			}
+		}
+	}
+
+	if len(komposeObject.NamedVolumes) != len(volumes) {
+		t.Errorf("Expected %d named volumes, got %v", len(volumes), komposeObject.NamedVolumes)
+	}
+	shared := komposeObject.NamedVolumes["shared-data"]
+	if !reflect.DeepEqual(shared.Consumers, []string{"web", "worker"}) || shared.AccessMode != "ReadWriteMany" {
+		t.Errorf("Expected shared-data to be mounted ReadWriteMany by web and worker, got %+v", shared)
+	}
+
+	volumes["fast"].Labels[LabelVolumeAccessMode] = "ReadWriteSometimes"
+	if err := handleV3Volume(&komposeObject, &volumes); err == nil {
+		t.Errorf("Expected an error for an invalid access mode")
+	}
+}
+
+func TestLoadV2NamedVolumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-v2-volumes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// libcompose prefixes the volumes with the project name, the name of the directory
+	if err := os.Mkdir(filepath.Join(dir, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "app", "docker-compose.yml")
+	content := `version: "2"
+services:
+  web:
+    image: nginx
+    volumes:
+      - shared_data:/data
+      - legacy:/legacy
+      - exports:/exports
+  worker:
+    image: busybox
+    volumes:
+      - shared_data:/data:ro
+volumes:
+  shared_data: {}
+  legacy:
+    external:
+      name: legacy-claim
+  exports:
+    driver_opts:
+      type: nfs
+      o: addr=10.0.0.5
+      device: ":/exports/data"
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Compose{}
+	komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]kobject.NamedVolume{
+		"app-shared-data": {Name: "app-shared-data", AccessMode: "ReadWriteMany", Consumers: []string{"web", "worker"}},
+		"legacy-claim":    {Name: "legacy-claim", External: true, ClaimName: "legacy-claim", Consumers: []string{"web"}},
+		"app-exports":     {Name: "app-exports", NFSServer: "10.0.0.5", NFSPath: "/exports/data", Consumers: []string{"web"}},
+	}
+	if !reflect.DeepEqual(komposeObject.NamedVolumes, expected) {
+		t.Errorf("Expected named volumes %+v, got %+v", expected, komposeObject.NamedVolumes)
+	}
+	for name, service := range komposeObject.ServiceConfigs {
+		for _, vol := range service.Volumes {
+			if shared := vol.VolumeName == "app-shared-data"; vol.Shared != shared {
+				t.Errorf("Expected volume %s of %s to be shared: %v, got %v", vol.VolumeName, name, shared, vol.Shared)
+			}
+		}
+	}
+	if len(komposeObject.Report.Entries) != 0 {
+		t.Errorf("Expected no report entry, got %+v", komposeObject.Report.Entries)
+	}
+}
+
 func TestLoadV3Ports(t *testing.T) {
 	for _, tt := range []struct {
 		desc   string
@@ -422,13 +551,35 @@ func TestUnsupportedKeys(t *testing.T) {
 
 	for name, test := range testCases {
 		t.Log("Test case:", name)
-		keys := checkUnsupportedKey(test.composeProject)
+		keys := checkUnsupportedKey(test.composeProject, nil)
 		if !reflect.DeepEqual(keys, test.expectedUnsupportedKeys) {
 			t.Errorf("ERROR: Expecting unsupported keys: ['%s']. Got: ['%s']", strings.Join(test.expectedUnsupportedKeys, "', '"), strings.Join(keys, "', '"))
 		}
 	}
 }
 
+// TestUnsupportedKeysForV3 test checkUnsupportedKeyForV3 reports a key
+// once whatever the number of services using it
+func TestUnsupportedKeysForV3(t *testing.T) {
+	ulimits := map[string]*types.UlimitsConfig{"nofile": {Soft: 1024, Hard: 2048}}
+	config := &types.Config{
+		Services: types.Services{
+			{Name: "foo", Ulimits: ulimits},
+			{Name: "bar", Ulimits: ulimits, CredentialSpec: types.CredentialSpecConfig{File: "spec.json"}},
+		},
+	}
+
+	report := kobject.NewReport(nil)
+	keys := checkUnsupportedKeyForV3(config, report)
+	expected := []string{"ulimits", "credential_spec"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected unsupported keys %v, got %v", expected, keys)
+	}
+	if len(report.Entries) != 3 {
+		t.Errorf("Expected 3 report entries, got %+v", report.Entries)
+	}
+}
+
 func TestNormalizeServiceNames(t *testing.T) {
 	testCases := []struct {
 		composeServiceName    string
@@ -500,7 +651,7 @@ func TestCheckPlacementCustomLabels(t *testing.T) {
 			"node.labels.monitor != xxx",
 		},
 	}
-	output := loadV3Placement(placement.Constraints)
+	output := loadV3Placement("foo", placement.Constraints, nil)
 
 	expected := map[string]string{"something": "anything"}
 
@@ -508,3 +659,533 @@ func TestCheckPlacementCustomLabels(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, output)
 	}
 }
+
+func TestLoadComposeSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "compose.yaml")
+	content := `name: shop
+x-common: &common
+  restart: always
+services:
+  web:
+    <<: *common
+    image: nginx
+    ports:
+      - "80:80"
+    depends_on:
+      db:
+        condition: service_healthy
+    mem_limit: 512m
+    x-team: frontend
+    deploy:
+      resources:
+        reservations:
+          devices:
+            - driver: nvidia
+              count: 2
+              capabilities: [gpu]
+  db:
+    image: postgres
+    profiles: ["debug", "db"]
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Compose{}
+	komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error loading compose specification file: %v", err)
+	}
+
+	if komposeObject.Name != "shop" {
+		t.Errorf("Expected project name shop, got %q", komposeObject.Name)
+	}
+	web, ok := komposeObject.ServiceConfigs["web"]
+	if !ok {
+		t.Fatalf("Service web not loaded")
+	}
+	if web.Image != "nginx" || web.Restart != "always" {
+		t.Errorf("Expected image nginx and restart always, got %q and %q", web.Image, web.Restart)
+	}
+	if web.MemLimit != yaml.MemStringorInt(512*1024*1024) {
+		t.Errorf("Expected mem_limit 512m, got %d", web.MemLimit)
+	}
+	if web.GPUs != 2 {
+		t.Errorf("Expected 2 GPUs, got %d", web.GPUs)
+	}
+	if !reflect.DeepEqual(web.DependsOn, map[string]string{"db": DependsOnServiceHealthy}) {
+		t.Errorf("Expected web to wait for db to be healthy, got %v", web.DependsOn)
+	}
+	db := komposeObject.ServiceConfigs["db"]
+	if !reflect.DeepEqual(db.Profiles, []string{"debug", "db"}) {
+		t.Errorf("Expected profiles [debug db], got %v", db.Profiles)
+	}
+}
+
+func TestLoadComposeSpecSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "compose.yaml")
+	content := `services:
+  app:
+    image: app
+    secrets:
+      - source: token
+        target: /etc/app/token
+secrets:
+  token:
+    environment: KOMPOSE_TEST_TOKEN
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Compose{}
+	if _, err := c.LoadFile([]string{file}, nil, nil); err == nil {
+		t.Errorf("Expected an error when the environment variable of a secret is not set")
+	}
+
+	os.Setenv("KOMPOSE_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("KOMPOSE_TEST_TOKEN")
+	komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error loading compose specification file: %v", err)
+	}
+	if komposeObject.SecretsContent["token"] != "s3cr3t" {
+		t.Errorf("Expected the content of secret token from the environment, got %v", komposeObject.SecretsContent)
+	}
+	if _, ok := komposeObject.ServiceConfigs["app"].SecretsMetaData["token"]; !ok {
+		t.Errorf("Expected the secrets of the project in the service metadata")
+	}
+}
+
+func TestLoadComposeSpecConfigs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "compose.yaml")
+	content := `services:
+  web:
+    image: nginx
+    configs:
+      - source: nginx_conf
+        target: /etc/nginx/conf.d/default.conf
+      - source: shared
+configs:
+  nginx_conf:
+    content: |
+      server { listen ${KOMPOSE_TEST_PORT}; }
+  shared:
+    external: true
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("KOMPOSE_TEST_PORT", "8080")
+	defer os.Unsetenv("KOMPOSE_TEST_PORT")
+	c := Compose{}
+	komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error loading compose specification file: %v", err)
+	}
+	if komposeObject.ConfigsContent["nginx_conf"] != "server { listen 8080; }\n" {
+		t.Errorf("Expected the interpolated content of config nginx_conf, got %q", komposeObject.ConfigsContent["nginx_conf"])
+	}
+	web := komposeObject.ServiceConfigs["web"]
+	if key, err := web.GetConfigMapKeyFromMeta("nginx_conf"); err != nil || key != "nginx_conf" {
+		t.Errorf("Expected key nginx_conf, got %q, %v", key, err)
+	}
+	if komposeObject.Report == nil {
+		t.Fatalf("Expected a report")
+	}
+	for _, entry := range komposeObject.Report.Entries {
+		if strings.HasPrefix(entry.Field, "configs") {
+			t.Errorf("Unexpected report entry %+v", entry)
+		}
+	}
+}
+
+func TestFilterServicesByProfiles(t *testing.T) {
+	services := types.Services{
+		{Name: "web", DependsOn: []string{"db"}},
+		{Name: "db"},
+		{Name: "mailcatcher"},
+		{Name: "debug", DependsOn: []string{"tracing"}},
+		{Name: "tracing"},
+	}
+	profiles := map[string][]string{
+		"mailcatcher": {"local"},
+		"debug":       {"debug"},
+		"db":          {"local"},
+		"tracing":     {"observability"},
+	}
+
+	testCases := map[string]struct {
+		enabledProfiles []string
+		expected        []string
+	}{
+		"No profile enabled":      {nil, []string{"web", "db"}},
+		"Enable debug profile":    {[]string{"debug"}, []string{"web", "db", "debug", "tracing"}},
+		"Enable all the profiles": {[]string{"*"}, []string{"web", "db", "mailcatcher", "debug", "tracing"}},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		var names []string
+		for _, service := range filterServicesByProfiles(services, profiles, test.enabledProfiles) {
+			names = append(names, service.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Expected services %v, got %v", test.expected, names)
+		}
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-interpolation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dotEnv := "IMAGE=nginx\nTAG=1.19\nEMPTY=\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, ".env"), []byte(dotEnv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, "prod.env")
+	if err := ioutil.WriteFile(envFile, []byte("IMAGE=httpd\nTAG=2.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	services := `
+  web:
+    image: ${IMAGE}:${TAG:?tag is required}
+    environment:
+      - PORT=${PORT:-8080}
+      - MODE=${EMPTY-production}
+      - COLOR=${EMPTY:-blue}
+      - PRICE=$$5`
+
+	testCases := map[string]struct {
+		header      string
+		services    string
+		envFiles    []string
+		image       string
+		expectedEnv map[string]string
+		err         string
+	}{
+		"Version 1 file with the .env file":   {"", services, nil, "nginx:1.19", map[string]string{"PORT": "8080", "MODE": "", "COLOR": "blue", "PRICE": "$5"}, ""},
+		"Version 2 file with the .env file":   {"version: '2'\nservices:", services, nil, "nginx:1.19", map[string]string{"PORT": "8080", "MODE": "", "COLOR": "blue", "PRICE": "$5"}, ""},
+		"Version 3 file with the .env file":   {"version: '3'\nservices:", services, nil, "nginx:1.19", map[string]string{"PORT": "8080", "MODE": "", "COLOR": "blue", "PRICE": "$5"}, ""},
+		"Compose Specification with env file": {"services:", services, []string{envFile}, "httpd:2.4", map[string]string{"PORT": "8080", "MODE": "production", "COLOR": "blue", "PRICE": "$5"}, ""},
+		"Version 2 file with env file":        {"version: '2'\nservices:", services, []string{envFile}, "httpd:2.4", map[string]string{"PORT": "8080", "MODE": "production", "COLOR": "blue", "PRICE": "$5"}, ""},
+		"Version 1 file missing a variable":   {"", "\n  web:\n    image: ${MISSING:?image is required}", nil, "", nil, "required variable MISSING is missing a value: image is required"},
+		"Version 3 file missing a variable":   {"version: '3'\nservices:", "\n  web:\n    image: ${MISSING:?image is required}", nil, "", nil, "required variable MISSING is missing a value: image is required"},
+		"Missing env file":                    {"version: '3'\nservices:", services, []string{filepath.Join(dir, "missing.env")}, "", nil, "unable to read env file"},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		file := filepath.Join(dir, "docker-compose.yml")
+		if err := ioutil.WriteFile(file, []byte(test.header+test.services+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := Compose{}
+		komposeObject, err := c.LoadFile([]string{file}, nil, test.envFiles)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing %q, got %v", test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		web := komposeObject.ServiceConfigs["web"]
+		if web.Image != test.image {
+			t.Errorf("Expected image %s, got %s", test.image, web.Image)
+		}
+		env := make(map[string]string)
+		for _, e := range web.Environment {
+			env[e.Name] = e.Value
+		}
+		if !reflect.DeepEqual(env, test.expectedEnv) {
+			t.Errorf("Expected environment %v, got %v", test.expectedEnv, env)
+		}
+	}
+}
+
+func TestExtends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	common := `
+  base:
+    image: nginx
+    environment:
+      - MODE=base
+      - LEVEL=info
+    labels:
+      team: web
+    ports:
+      - "80"
+    volumes:
+      - ./html:/usr/share/nginx/html
+      - ./conf:/etc/nginx/conf.d
+  cycle:
+    extends:
+      file: ../docker-compose.yml
+      service: loop`
+	services := `
+  web:
+    extends:
+      file: common/common.yml
+      service: base
+    environment:
+      MODE: web
+    ports:
+      - "443"
+    volumes:
+      - ./conf:/etc/nginx/conf.d
+  worker:
+    extends: web
+    command: ["work"]`
+
+	testCases := map[string]struct {
+		header   string
+		services string
+	}{
+		"Version 2 files":                          {"version: '2'\nservices:", services},
+		"Version 3 files":                          {"version: '3'\nservices:", services},
+		"Compose Specification files":              {"services:", services},
+		"Version 2 files with a cycle":             {"version: '2'\nservices:", "\n  loop:\n    extends:\n      file: common/common.yml\n      service: cycle"},
+		"Compose Specification files with a cycle": {"services:", "\n  loop:\n    extends:\n      file: common/common.yml\n      service: cycle"},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		if err := ioutil.WriteFile(filepath.Join(dir, "common", "common.yml"), []byte(test.header+common+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "docker-compose.yml")
+		if err := ioutil.WriteFile(file, []byte(test.header+test.services+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := Compose{}
+		komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+		if strings.Contains(name, "cycle") {
+			expected := fmt.Sprintf("extends cycle detected: service loop in %s -> service cycle in %s -> service loop in %s",
+				file, filepath.Join(dir, "common", "common.yml"), file)
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error %q, got %v", expected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, service := range []string{"web", "worker"} {
+			serviceConfig := komposeObject.ServiceConfigs[service]
+			if serviceConfig.Image != "nginx" {
+				t.Errorf("Expected %s to inherit image nginx, got %q", service, serviceConfig.Image)
+			}
+			env := make(map[string]string)
+			for _, e := range serviceConfig.Environment {
+				env[e.Name] = e.Value
+			}
+			if !reflect.DeepEqual(env, map[string]string{"MODE": "web", "LEVEL": "info"}) {
+				t.Errorf("Expected %s environment to be merged, got %v", service, env)
+			}
+			if serviceConfig.Labels["team"] != "web" {
+				t.Errorf("Expected %s to inherit label team, got %v", service, serviceConfig.Labels)
+			}
+			if len(serviceConfig.Port) != 2 {
+				t.Errorf("Expected %s ports to be concatenated, got %v", service, serviceConfig.Port)
+			}
+			hosts := make(map[string]string)
+			for _, volume := range serviceConfig.Volumes {
+				hosts[volume.Container] = volume.Host
+			}
+			if !strings.HasSuffix(hosts["/usr/share/nginx/html"], filepath.Join("common", "html")) {
+				t.Errorf("Expected %s html volume relative to common.yml, got %q", service, hosts["/usr/share/nginx/html"])
+			}
+			if strings.Contains(hosts["/etc/nginx/conf.d"], "common") {
+				t.Errorf("Expected %s conf volume to be overridden, got %q", service, hosts["/etc/nginx/conf.d"])
+			}
+		}
+		if !reflect.DeepEqual(komposeObject.ServiceConfigs["worker"].Args, []string{"work"}) {
+			t.Errorf("Expected worker command [work], got %v", komposeObject.ServiceConfigs["worker"].Args)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"db/.env":         "DB_IMAGE=postgres\n",
+		"db/compose.yaml": "services:\n  db:\n    image: ${DB_IMAGE}\n    volumes:\n      - ./data:/var/lib/postgresql/data\n",
+		"db/cycle.yaml":   "include:\n  - ../cycle.yaml\nservices:\n  other:\n    image: busybox\n",
+		"compose.yaml":    "include:\n  - db/compose.yaml\nservices:\n  web:\n    image: nginx\n    depends_on:\n      - db\n",
+		"conflict.yaml":   "include:\n  - path: db/compose.yaml\nservices:\n  db:\n    image: mysql\n",
+		"cycle.yaml":      "include:\n  - db/cycle.yaml\nservices:\n  web:\n    image: nginx\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := Compose{}
+	komposeObject, err := c.LoadFile([]string{filepath.Join(dir, "compose.yaml")}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	db, ok := komposeObject.ServiceConfigs["db"]
+	if !ok {
+		t.Fatalf("Included service db not loaded")
+	}
+	if db.Image != "postgres" {
+		t.Errorf("Expected db image postgres from the .env file of db, got %q", db.Image)
+	}
+	if len(db.Volumes) != 1 || db.Volumes[0].Host != filepath.Join(dir, "db", "data") {
+		t.Errorf("Expected db volume relative to db/compose.yaml, got %v", db.Volumes)
+	}
+	if _, ok := komposeObject.ServiceConfigs["web"]; !ok {
+		t.Errorf("Service web not loaded")
+	}
+
+	_, err = c.LoadFile([]string{filepath.Join(dir, "conflict.yaml")}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "service db included from db/compose.yaml conflicts") {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+
+	_, err = c.LoadFile([]string{filepath.Join(dir, "cycle.yaml")}, nil, nil)
+	expected := fmt.Sprintf("include cycle detected: %s -> %s -> %s",
+		filepath.Join(dir, "cycle.yaml"), filepath.Join(dir, "db", "cycle.yaml"), filepath.Join(dir, "cycle.yaml"))
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	services := `
+  web_app:
+    image: nginx
+    restart: unless-stopped`
+
+	testCases := map[string]struct {
+		header   string
+		services string
+		expected []kobject.ReportEntry
+	}{
+		"Version 2 file": {"version: '2'\nservices:", services + "\n    cgroup_parent: m-executor", []kobject.ReportEntry{
+			{Service: "web_app", Line: 3, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 5, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+			{Service: "web_app", Field: "cgroup_parent", Line: 6, Action: kobject.ReportDropped, Reason: "cgroup_parent key is not supported"},
+		}},
+		"Version 3 file": {"version: '3'\nservices:", services, []kobject.ReportEntry{
+			{Service: "web_app", Line: 3, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 5, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+		}},
+		"Version 3 file with ulimits": {"version: '3'\nservices:", services + "\n    ulimits:\n      nproc: 65535\n      nofile: 20000", []kobject.ReportEntry{
+			{Service: "web_app", Line: 3, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 5, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+			{Service: "web_app", Field: "ulimits", Line: 6, Action: kobject.ReportDropped, Reason: "ulimits nofile, nproc have no Kubernetes equivalent, they are set by the container runtime of the nodes"},
+		}},
+		"Compose Specification with all GPUs": {"services:", services + "\n    deploy:\n      resources:\n        reservations:\n          devices:\n            - capabilities: [gpu]", []kobject.ReportEntry{
+			{Service: "web_app", Line: 2, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 4, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+			{Service: "web_app", Field: "deploy.resources.reservations.devices", Line: 8, Action: kobject.ReportApproximated, Reason: "all the GPUs of a node can't be requested, 1 GPU is requested"},
+		}},
+		"Compose Specification with unknown keys": {"services:", services + "\n    imgae: nginx\n    x-team: web\nvolume:\n  data: {}", []kobject.ReportEntry{
+			{Service: "web_app", Line: 2, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 4, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+			{Service: "web_app", Field: "imgae", Line: 5, Action: kobject.ReportDropped, Reason: "imgae key is unknown to the Compose file format 3.9"},
+			{Field: "volume", Line: 7, Action: kobject.ReportDropped, Reason: "volume key is unknown to the Compose file format 3.9"},
+		}},
+		"Compose Specification": {"services:", services, []kobject.ReportEntry{
+			{Service: "web_app", Line: 2, Action: kobject.ReportRenamed, Reason: `service "web_app" is renamed to "web-app"`},
+			{Service: "web_app", Field: "restart", Line: 4, Action: kobject.ReportApproximated, Reason: "restart policy 'unless-stopped' is not supported, converted to 'always'"},
+		}},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		file := filepath.Join(dir, "docker-compose.yml")
+		if err := ioutil.WriteFile(file, []byte(test.header+test.services+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := Compose{}
+		komposeObject, err := c.LoadFile([]string{file}, nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		komposeObject.Report.Sort()
+		for i := range test.expected {
+			test.expected[i].File = file
+		}
+		if !reflect.DeepEqual(komposeObject.Report.Entries, test.expected) {
+			t.Errorf("Expected report %+v, got %+v", test.expected, komposeObject.Report.Entries)
+		}
+	}
+}
+
+func TestEnvLookup(t *testing.T) {
+	lookup := envLookup{"TAG": "1.19", "EMPTY": ""}
+
+	testCases := map[string]struct {
+		key      string
+		expected []string
+	}{
+		"Variable with a value":  {"TAG", []string{"TAG=1.19"}},
+		"Variable set but empty": {"EMPTY", []string{"EMPTY="}},
+		"Variable missing":       {"MISSING", []string{}},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		values := lookup.Lookup(test.key, nil)
+		if !reflect.DeepEqual(values, test.expected) {
+			t.Errorf("Expected %v, got %v", test.expected, values)
+		}
+	}
+}
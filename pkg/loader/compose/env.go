@@ -0,0 +1,157 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/interpolation"
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/template"
+	"github.com/docker/libcompose/config"
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultEnvFile is the file of the project directory holding the variables
+// used for interpolation when no env file is given
+const DefaultEnvFile = ".env"
+
+// buildEnvironment returns the variables used for interpolation.
+// They are read from the env files, or from the .env file of the project directory
+// if there are none, and the variables of the shell take precedence over them.
+// The shell part is based on https://github.com/docker/cli/blob/5dd30732a23bbf14db1c64d084ae4a375f592cfa/cli/command/stack/deploy_composefile.go#L143
+func buildEnvironment(files []string, envFiles []string) (map[string]string, error) {
+	workingDir, err := getComposeFileDir(files)
+	if err != nil {
+		return nil, err
+	}
+	return buildProjectEnvironment(workingDir, envFiles)
+}
+
+// buildProjectEnvironment returns the variables used for interpolation in a project directory
+func buildProjectEnvironment(workingDir string, envFiles []string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	if len(envFiles) == 0 {
+		dotEnv := filepath.Join(workingDir, DefaultEnvFile)
+		if _, err := os.Stat(dotEnv); err == nil {
+			envFiles = []string{dotEnv}
+		}
+	}
+
+	// later env files override the variables of the previous ones
+	for _, envFile := range envFiles {
+		values, err := godotenv.Read(envFile)
+		if err != nil {
+			return result, errors.Wrapf(err, "unable to read env file %s", envFile)
+		}
+		for key, value := range values {
+			result[key] = value
+		}
+	}
+
+	for _, s := range os.Environ() {
+		// if value is empty, s is like "K=", not "K".
+		if !strings.Contains(s, "=") {
+			return result, errors.Errorf("unexpected environment %q", s)
+		}
+		kv := strings.SplitN(s, "=", 2)
+		result[kv[0]] = kv[1]
+	}
+	return result, nil
+}
+
+// substituteVariables substitutes the variables of a value like docker-compose does,
+// a ${VAR:?err} or ${VAR?err} variable missing a value is reported as a plain error
+// rather than an invalid interpolation format
+func substituteVariables(value string, mapping template.Mapping) (string, error) {
+	result, err := template.Substitute(value, mapping)
+	if invalid, ok := err.(*template.InvalidTemplateError); ok && strings.HasPrefix(invalid.Template, "required variable") {
+		return result, errors.New(invalid.Template)
+	}
+	return result, err
+}
+
+// withRequiredVariables makes the docker/cli loader report the required variables missing a value
+func withRequiredVariables(opts *loader.Options) {
+	opts.Interpolate.Substitute = substituteVariables
+}
+
+// prepareV1V2File resolves the extends key and substitutes the variables of a version 1 or 2 file.
+// libcompose only knows about ${VAR} and ${VAR:-default}, so the file is interpolated
+// the way version 3 files are and every $ left is escaped for libcompose.
+func prepareV1V2File(file string, loadedFile []byte, env map[string]string) ([]byte, error) {
+	parsedComposeFile, err := loader.ParseYAML(loadedFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", file)
+	}
+
+	if err := resolveExtends(file, parsedComposeFile); err != nil {
+		return nil, err
+	}
+
+	interpolated, err := interpolation.Interpolate(parsedComposeFile, interpolation.Options{
+		LookupValue: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+		Substitute: substituteVariables,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to interpolate %s", file)
+	}
+
+	return yaml.Marshal(escapeDollarSigns(interpolated))
+}
+
+// escapeDollarSigns escapes the $ of every string value
+func escapeDollarSigns(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.Replace(v, "$", "$$", -1)
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = escapeDollarSigns(elem)
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = escapeDollarSigns(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// envLookup looks the variables of the environment entries without a value up
+// in the environment built from the env files and the shell
+type envLookup map[string]string
+
+// Lookup implements the libcompose EnvironmentLookup interface
+func (e envLookup) Lookup(key string, config *config.ServiceConfig) []string {
+	value, ok := e[key]
+	if !ok {
+		return []string{}
+	}
+	return []string{fmt.Sprintf("%s=%s", key, value)}
+}
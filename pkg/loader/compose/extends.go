@@ -0,0 +1,413 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/pkg/errors"
+)
+
+// extendsNotShared are the keys of a service which are never shared with the services extending it
+var extendsNotShared = []string{"links", "volumes_from", "depends_on"}
+
+// extendsResolver resolves the extends key of the services of a parsed compose file,
+// the files referenced by extends are read once
+type extendsResolver struct {
+	configs map[string]map[string]interface{}
+}
+
+// resolveExtends replaces the services of a parsed compose file extending other services
+// by the result of merging them with the services they extend
+func resolveExtends(file string, config map[string]interface{}) error {
+	file = absComposeFile(file)
+	r := &extendsResolver{
+		configs: map[string]map[string]interface{}{file: config},
+	}
+
+	services := composeServices(config)
+	for name, s := range services {
+		service, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := service["extends"]; !ok {
+			continue
+		}
+		resolved, err := r.resolve(file, name, nil)
+		if err != nil {
+			return err
+		}
+		services[name] = resolved
+	}
+	return nil
+}
+
+// resolve returns a service of a file once merged with the chain of services it extends,
+// the chain is kept to report cycles
+func (r *extendsResolver) resolve(file string, name string, chain []string) (map[string]interface{}, error) {
+	services, err := r.services(file)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := services[name]
+	if !ok {
+		return nil, errors.Errorf("unable to extend service %s: no such service in %s", name, file)
+	}
+	service, _ := s.(map[string]interface{})
+	service = deepCopy(service).(map[string]interface{})
+
+	extends, ok := service["extends"]
+	if !ok {
+		return service, nil
+	}
+	delete(service, "extends")
+
+	baseName, baseFile, err := parseExtends(extends)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid extends of service %s in %s", name, file)
+	}
+	dir, err := getComposeFileDir([]string{file})
+	if err != nil {
+		return nil, err
+	}
+	if baseFile == "" {
+		baseFile = file
+	} else if !filepath.IsAbs(baseFile) {
+		baseFile = filepath.Join(dir, baseFile)
+	}
+
+	chain = append(chain, fmt.Sprintf("service %s in %s", name, file))
+	base := fmt.Sprintf("service %s in %s", baseName, baseFile)
+	for _, link := range chain {
+		if link == base {
+			return nil, errors.Errorf("extends cycle detected: %s", strings.Join(append(chain, base), " -> "))
+		}
+	}
+
+	baseService, err := r.resolve(baseFile, baseName, chain)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range extendsNotShared {
+		delete(baseService, key)
+	}
+
+	// relative paths of the base service are relative to its own file
+	baseDir, err := getComposeFileDir([]string{baseFile})
+	if err != nil {
+		return nil, err
+	}
+	if baseDir != dir {
+		rebaseServicePaths(baseService, baseDir, dir)
+	}
+
+	return mergeServices(baseService, service), nil
+}
+
+// services returns the services of a file, reading it if needed
+func (r *extendsResolver) services(file string) (map[string]interface{}, error) {
+	if config, ok := r.configs[file]; ok {
+		return composeServices(config), nil
+	}
+
+	loadedFile, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read extended file %s", file)
+	}
+	config, err := loader.ParseYAML(loadedFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse extended file %s", file)
+	}
+	r.configs[file] = config
+	return composeServices(config), nil
+}
+
+// composeServices returns the services of a parsed compose file,
+// version 1 files declare them at the top level
+func composeServices(config map[string]interface{}) map[string]interface{} {
+	if _, ok := config["services"]; ok {
+		services, _ := config["services"].(map[string]interface{})
+		return services
+	}
+	if _, ok := config["version"]; ok {
+		return nil
+	}
+	return config
+}
+
+// absComposeFile returns the absolute path of a compose file, stdin is left as is
+func absComposeFile(file string) string {
+	if file == "-" {
+		return file
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
+
+// parseExtends returns the service and the file of an extends key,
+// which is either the name of a service of the same file or an object
+func parseExtends(extends interface{}) (string, string, error) {
+	switch e := extends.(type) {
+	case string:
+		return e, "", nil
+	case map[string]interface{}:
+		service, ok := e["service"].(string)
+		if !ok || service == "" {
+			return "", "", errors.New("extends must name a service")
+		}
+		file, _ := e["file"].(string)
+		return service, file, nil
+	default:
+		return "", "", fmt.Errorf("%v is not a service nor an object", extends)
+	}
+}
+
+// mergeServices merges a service in the service it extends following the rules of docker-compose:
+// mappings are merged, the sequences of single values are concatenated,
+// the volumes and devices are merged by mount path and the other values are overridden
+func mergeServices(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		baseValue, ok := base[key]
+		if !ok || baseValue == nil {
+			base[key] = value
+			continue
+		}
+
+		switch key {
+		case "environment":
+			base[key] = mergeMaps(toMapping(baseValue, true), toMapping(value, true))
+		case "labels", "sysctls", "annotations":
+			base[key] = mergeMaps(toMapping(baseValue, false), toMapping(value, false))
+		case "volumes", "devices":
+			base[key] = mergeByTarget(toSequence(baseValue), toSequence(value))
+		case "ports", "expose", "dns", "dns_search", "dns_opt", "tmpfs", "external_links", "extra_hosts",
+			"cap_add", "cap_drop", "env_file", "security_opt":
+			base[key] = mergeSequences(toSequence(baseValue), toSequence(value))
+		case "build":
+			base[key] = mergeMaps(toBuild(baseValue), toBuild(value))
+		default:
+			baseMap, baseIsMap := baseValue.(map[string]interface{})
+			valueMap, valueIsMap := value.(map[string]interface{})
+			if baseIsMap && valueIsMap {
+				base[key] = mergeMaps(baseMap, valueMap)
+			} else {
+				base[key] = value
+			}
+		}
+	}
+	return base
+}
+
+// mergeMaps merges two mappings recursively, the values of override take precedence
+func mergeMaps(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		baseMap, baseIsMap := base[key].(map[string]interface{})
+		valueMap, valueIsMap := value.(map[string]interface{})
+		if baseIsMap && valueIsMap {
+			base[key] = mergeMaps(baseMap, valueMap)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
+
+// mergeSequences concatenates two sequences, skipping the values already in base
+func mergeSequences(base []interface{}, override []interface{}) []interface{} {
+	for _, value := range override {
+		found := false
+		for _, baseValue := range base {
+			if reflect.DeepEqual(baseValue, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			base = append(base, value)
+		}
+	}
+	return base
+}
+
+// mergeByTarget merges volumes or devices, an entry of override replaces the one of base
+// mounted at the same path
+func mergeByTarget(base []interface{}, override []interface{}) []interface{} {
+	targets := make(map[string]bool, len(override))
+	for _, value := range override {
+		targets[mountTarget(value)] = true
+	}
+
+	var merged []interface{}
+	for _, value := range base {
+		if !targets[mountTarget(value)] {
+			merged = append(merged, value)
+		}
+	}
+	return append(merged, override...)
+}
+
+// mountTarget returns the path a volume or device is mounted at
+func mountTarget(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		parts := strings.Split(v, ":")
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return parts[1]
+	case map[string]interface{}:
+		if target, ok := v["target"].(string); ok {
+			return target
+		}
+		return fmt.Sprintf("%v", v["container_path"])
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// toMapping converts a list of KEY=VALUE to a mapping, a KEY without value is nil
+// for environment variables and empty otherwise
+func toMapping(value interface{}, nilWithoutValue bool) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case []interface{}:
+		mapping := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			kv := strings.SplitN(fmt.Sprintf("%v", item), "=", 2)
+			switch {
+			case len(kv) == 2:
+				mapping[kv[0]] = kv[1]
+			case nilWithoutValue:
+				mapping[kv[0]] = nil
+			default:
+				mapping[kv[0]] = ""
+			}
+		}
+		return mapping
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// toSequence converts a single value to a sequence
+func toSequence(value interface{}) []interface{} {
+	if sequence, ok := value.([]interface{}); ok {
+		return sequence
+	}
+	return []interface{}{value}
+}
+
+// toBuild converts the short syntax of build to the long one
+func toBuild(value interface{}) map[string]interface{} {
+	if build, ok := value.(map[string]interface{}); ok {
+		return build
+	}
+	return map[string]interface{}{"context": value}
+}
+
+// rebaseServicePaths rewrites the relative paths of the build context, env files and bind mounts
+// of a service so that they are relative to another directory
+func rebaseServicePaths(service map[string]interface{}, from string, to string) {
+	switch build := service["build"].(type) {
+	case string:
+		service["build"] = rebasePath(build, from, to)
+	case map[string]interface{}:
+		if context, ok := build["context"].(string); ok {
+			build["context"] = rebasePath(context, from, to)
+		}
+	}
+
+	switch envFile := service["env_file"].(type) {
+	case string:
+		service["env_file"] = rebasePath(envFile, from, to)
+	case []interface{}:
+		for i, e := range envFile {
+			switch path := e.(type) {
+			case string:
+				envFile[i] = rebasePath(path, from, to)
+			case map[string]interface{}:
+				if p, ok := path["path"].(string); ok {
+					path["path"] = rebasePath(p, from, to)
+				}
+			}
+		}
+	}
+
+	if volumes, ok := service["volumes"].([]interface{}); ok {
+		for i, v := range volumes {
+			switch volume := v.(type) {
+			case string:
+				if strings.HasPrefix(volume, ".") {
+					parts := strings.SplitN(volume, ":", 2)
+					parts[0] = rebasePath(parts[0], from, to)
+					volumes[i] = strings.Join(parts, ":")
+				}
+			case map[string]interface{}:
+				if source, ok := volume["source"].(string); ok && strings.HasPrefix(source, ".") {
+					volume["source"] = rebasePath(source, from, to)
+				}
+			}
+		}
+	}
+}
+
+// rebasePath rewrites a path relative to a directory so that it is relative to another one,
+// absolute paths, home directories, variables and remote build contexts are left as is
+func rebasePath(path string, from string, to string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") || strings.HasPrefix(path, "$") ||
+		strings.Contains(path, "://") || strings.HasPrefix(path, "git@") || strings.HasPrefix(path, "github.com/") {
+		return path
+	}
+
+	rel, err := filepath.Rel(to, filepath.Join(from, path))
+	if err != nil {
+		return filepath.Join(from, path)
+	}
+	if !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// deepCopy copies the mappings and sequences of a parsed compose file
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			copied[key] = deepCopy(elem)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = deepCopy(elem)
+		}
+		return copied
+	default:
+		return v
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kubernetes/kompose/pkg/kobject"
+)
+
+// sourceLocator finds the file and the line of the fields of the services in the compose files
+type sourceLocator struct {
+	files     []string
+	documents map[string]*yaml.Node
+}
+
+// newReport creates a conversion report locating its entries in the given compose files
+func newReport(files []string, contents composeFiles) *kobject.Report {
+	l := &sourceLocator{files: files, documents: make(map[string]*yaml.Node, len(files))}
+	for _, file := range files {
+		var document yaml.Node
+		// the files failing to parse are reported by the loaders, their entries are just not located
+		if err := yaml.Unmarshal(contents[file], &document); err != nil || len(document.Content) == 0 {
+			continue
+		}
+		l.documents[file] = document.Content[0]
+	}
+	return kobject.NewReport(l.locate)
+}
+
+// locate returns the file and the line of a field of a service, or of the service itself if the field is empty.
+// A service is looked up by its name in the compose file or by its normalized name,
+// the last file defining the field wins as later files override the previous ones.
+func (l *sourceLocator) locate(service string, field string) (string, int) {
+	var fallbackFile string
+	var fallbackLine int
+
+	for i := len(l.files) - 1; i >= 0; i-- {
+		file := l.files[i]
+		root, ok := l.documents[file]
+		if !ok {
+			continue
+		}
+
+		node := root
+		line := 0
+		if service != "" {
+			key, value := lookupServiceNode(root, service)
+			if key == nil {
+				continue
+			}
+			if fallbackFile == "" {
+				fallbackFile, fallbackLine = file, key.Line
+			}
+			if field == "" {
+				return file, key.Line
+			}
+			node, line = value, key.Line
+		}
+
+		found := false
+		for _, part := range strings.Split(field, ".") {
+			key, value := lookupNode(node, func(name string) bool { return name == part })
+			if key == nil {
+				break
+			}
+			found = true
+			node, line = value, key.Line
+		}
+		if found {
+			return file, line
+		}
+	}
+	return fallbackFile, fallbackLine
+}
+
+// lookupServiceNode returns the key and the value of a service of a parsed compose file,
+// version 1 files declare the services at the top level
+func lookupServiceNode(root *yaml.Node, service string) (*yaml.Node, *yaml.Node) {
+	services := root
+	if _, value := lookupNode(root, func(name string) bool { return name == "services" }); value != nil {
+		services = value
+	} else if key, _ := lookupNode(root, func(name string) bool { return name == "version" }); key != nil {
+		return nil, nil
+	}
+	return lookupNode(services, func(name string) bool {
+		return name == service || normalizeServiceNames(name) == service
+	})
+}
+
+// lookupNode returns the first key of a mapping node matching a name, and its value
+func lookupNode(mapping *yaml.Node, match func(string) bool) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if match(mapping.Content[i].Value) {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,784 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/kubernetes/kompose/pkg/kobject"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// composeSpecVersion is the docker/cli schema version used to load
+// Compose Specification files, it is the closest one to the specification
+const composeSpecVersion = "3.9"
+
+// composeSpecTopLevelKeys and composeSpecServiceKeys are the keys of the docker/cli schema of composeSpecVersion,
+// the loader drops the other keys without validating them
+var composeSpecTopLevelKeys = map[string]bool{
+	"configs": true, "networks": true, "secrets": true, "services": true, "version": true, "volumes": true,
+}
+
+var composeSpecServiceKeys = map[string]bool{
+	"build": true, "cap_add": true, "cap_drop": true, "cgroup_parent": true, "cgroupns_mode": true, "command": true,
+	"configs": true, "container_name": true, "credential_spec": true, "depends_on": true, "deploy": true, "devices": true,
+	"dns": true, "dns_search": true, "domainname": true, "entrypoint": true, "env_file": true, "environment": true,
+	"expose": true, "external_links": true, "extra_hosts": true, "healthcheck": true, "hostname": true, "image": true,
+	"init": true, "ipc": true, "isolation": true, "labels": true, "links": true, "logging": true, "mac_address": true,
+	"network_mode": true, "networks": true, "pid": true, "ports": true, "privileged": true, "read_only": true,
+	"restart": true, "secrets": true, "security_opt": true, "shm_size": true, "stdin_open": true,
+	"stop_grace_period": true, "stop_signal": true, "sysctls": true, "tmpfs": true, "tty": true, "ulimits": true,
+	"user": true, "userns_mode": true, "volumes": true, "working_dir": true,
+}
+
+// composeSpecFile holds the Compose Specification keys of a file that
+// the docker/cli loader doesn't know about
+type composeSpecFile struct {
+	Name      string
+	Include   []specInclude
+	Profiles  map[string][]string
+	DependsOn map[string]map[string]string
+	DNSOpts   map[string][]string
+	Devices   map[string][]specDeviceRequest
+	// SecretEnvironments gives the environment variable holding the content of a secret
+	SecretEnvironments map[string]string
+	// ConfigContents holds the content of the configs defined inline, before interpolation
+	ConfigContents map[string]string
+	// UnknownKeys are the keys taken out of the file because the loader would drop them silently
+	UnknownKeys []specKey
+}
+
+// specKey is a key of a service, or a top-level key when Service is empty
+type specKey struct {
+	Service string
+	Key     string
+}
+
+// specDeviceRequest is an entry of deploy.resources.reservations.devices,
+// Count is empty when all the devices are requested
+type specDeviceRequest struct {
+	Capabilities []string
+	Count        string
+	DeviceIDs    []string
+}
+
+// specInclude is an entry of the include key, the paths are relative to the including file
+type specInclude struct {
+	Paths            []string
+	ProjectDirectory string
+	EnvFiles         []string
+}
+
+// specLoader loads the files of a Compose Specification project along with the files they include,
+// and gathers the keys of their services that docker/cli doesn't know about
+type specLoader struct {
+	files     composeFiles
+	profiles  map[string][]string
+	dependsOn map[string]map[string]string
+	dnsOpts   map[string][]string
+	devices   map[string][]specDeviceRequest
+	secrets   map[string]string
+	configs   map[string]string
+	unknown   []specKey
+}
+
+// isComposeSpecFile checks if a versionless file follows the Compose Specification.
+// Version 1 files have no version either, but they declare services at the top level
+// instead of under the "services" key.
+func isComposeSpecFile(loadedFile []byte) (bool, error) {
+	type ComposeSpec struct {
+		Services map[string]interface{} `json:"services"`
+		Include  []interface{}          `json:"include"`
+	}
+	var spec ComposeSpec
+
+	err := yaml.Unmarshal(loadedFile, &spec)
+	if err != nil {
+		return false, err
+	}
+
+	return len(spec.Services) > 0 || len(spec.Include) > 0, nil
+}
+
+// parseComposeSpec parses files following the Compose Specification (https://github.com/compose-spec/compose-spec).
+// The files are loaded with the latest docker/cli schema once the keys unknown to docker/cli are taken out.
+func parseComposeSpec(files []string, contents composeFiles, enabledProfiles []string, envFiles []string, report *kobject.Report) (kobject.KomposeObject, error) {
+	// Gather the working directory
+	workingDir, err := getComposeFileDir(files)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
+
+	// get environment variables
+	env, err := buildEnvironment(files, envFiles)
+	if err != nil {
+		return kobject.KomposeObject{}, errors.Wrap(err, "cannot build environment variables")
+	}
+
+	l := &specLoader{
+		files:     contents,
+		profiles:  make(map[string][]string),
+		dependsOn: make(map[string]map[string]string),
+		dnsOpts:   make(map[string][]string),
+		devices:   make(map[string][]specDeviceRequest),
+		secrets:   make(map[string]string),
+		configs:   make(map[string]string),
+	}
+
+	var name string
+	var config *types.Config
+	for _, file := range files {
+		currentConfig, spec, err := l.load(file, workingDir, env, nil)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+
+		// later files override the project name
+		if spec.Name != "" {
+			name, err = substituteVariables(spec.Name, func(key string) (string, bool) {
+				value, ok := env[key]
+				return value, ok
+			})
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrapf(err, "invalid project name in %s", file)
+			}
+		}
+
+		if config == nil {
+			config = currentConfig
+		} else {
+			config, err = mergeComposeObject(config, currentConfig)
+			if err != nil {
+				return kobject.KomposeObject{}, err
+			}
+		}
+	}
+
+	if len(enabledProfiles) == 0 {
+		if composeProfiles, ok := env["COMPOSE_PROFILES"]; ok && composeProfiles != "" {
+			enabledProfiles = strings.Split(composeProfiles, ",")
+		}
+	}
+	config.Services = filterServicesByProfiles(config.Services, l.profiles, enabledProfiles)
+
+	noSupKeys := checkUnsupportedKeyForV3(config, report)
+	for _, keyName := range noSupKeys {
+		report.Warn("", keyName, "Unsupported %s key - ignoring", keyName)
+	}
+	for _, key := range l.unknown {
+		report.Add(kobject.ReportEntry{Service: key.Service, Field: key.Key, Action: kobject.ReportDropped, Reason: fmt.Sprintf("%s key is unknown to the Compose file format %s", key.Key, composeSpecVersion)})
+		if key.Service == "" {
+			report.Warn("", key.Key, "Unknown %s key - ignoring", key.Key)
+		} else {
+			report.Warn(key.Service, key.Key, "Unknown %s key of service %s - ignoring", key.Key, key.Service)
+		}
+	}
+
+	komposeObject, err := dockerComposeToKomposeMapping(config, report)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
+	komposeObject.Name = name
+
+	for service, serviceProfiles := range l.profiles {
+		serviceConfig, ok := komposeObject.ServiceConfigs[normalizeServiceNames(service)]
+		if !ok {
+			continue
+		}
+		serviceConfig.Profiles = serviceProfiles
+		komposeObject.ServiceConfigs[normalizeServiceNames(service)] = serviceConfig
+	}
+
+	// the long syntax of depends_on also gives the condition to wait for
+	for service, conditions := range l.dependsOn {
+		serviceConfig, ok := komposeObject.ServiceConfigs[normalizeServiceNames(service)]
+		if !ok {
+			continue
+		}
+		serviceConfig.DependsOn = make(map[string]string, len(conditions))
+		for dependency, condition := range conditions {
+			serviceConfig.DependsOn[normalizeServiceNames(dependency)] = condition
+		}
+		komposeObject.ServiceConfigs[normalizeServiceNames(service)] = serviceConfig
+	}
+
+	for service, options := range l.dnsOpts {
+		serviceConfig, ok := komposeObject.ServiceConfigs[normalizeServiceNames(service)]
+		if !ok {
+			continue
+		}
+		serviceConfig.DNSOpts = options
+		komposeObject.ServiceConfigs[normalizeServiceNames(service)] = serviceConfig
+	}
+
+	for service, requests := range l.devices {
+		serviceConfig, ok := komposeObject.ServiceConfigs[normalizeServiceNames(service)]
+		if !ok {
+			continue
+		}
+		serviceConfig.GPUs = countGPUs(service, requests, report)
+		komposeObject.ServiceConfigs[normalizeServiceNames(service)] = serviceConfig
+	}
+
+	if len(l.secrets) > 0 {
+		komposeObject.SecretsContent = l.secrets
+	}
+	if len(l.configs) > 0 {
+		komposeObject.ConfigsContent = l.configs
+		// docker/cli resolves the missing file of the configs defined inline to the working directory
+		for _, serviceConfig := range komposeObject.ServiceConfigs {
+			for name := range l.configs {
+				if config, ok := serviceConfig.ConfigsMetaData[name]; ok {
+					config.File = ""
+					serviceConfig.ConfigsMetaData[name] = config
+				}
+			}
+		}
+	}
+
+	return komposeObject, nil
+}
+
+// load loads a file and the files it includes, the chain of including files is kept to report cycles
+func (l *specLoader) load(file string, workingDir string, env map[string]string, chain []string) (*types.Config, composeSpecFile, error) {
+	absFile := absComposeFile(file)
+	for _, link := range chain {
+		if link == absFile {
+			return nil, composeSpecFile{}, errors.Errorf("include cycle detected: %s", strings.Join(append(chain, absFile), " -> "))
+		}
+	}
+	chain = append(chain, absFile)
+
+	loadedFile, err := l.files.read(file)
+	if err != nil {
+		return nil, composeSpecFile{}, err
+	}
+
+	parsedComposeFile, err := loader.ParseYAML(loadedFile)
+	if err != nil {
+		return nil, composeSpecFile{}, err
+	}
+
+	if err := resolveExtends(file, parsedComposeFile); err != nil {
+		return nil, composeSpecFile{}, err
+	}
+
+	spec, err := normalizeComposeSpec(parsedComposeFile)
+	if err != nil {
+		return nil, composeSpecFile{}, errors.Wrapf(err, "unable to parse %s", file)
+	}
+
+	// later files override the profiles of a service
+	for service, serviceProfiles := range spec.Profiles {
+		l.profiles[service] = serviceProfiles
+	}
+	for service, conditions := range spec.DependsOn {
+		l.dependsOn[service] = conditions
+	}
+	for service, options := range spec.DNSOpts {
+		l.dnsOpts[service] = options
+	}
+	for service, requests := range spec.Devices {
+		l.devices[service] = requests
+	}
+	l.unknown = append(l.unknown, spec.UnknownKeys...)
+	// the secrets are read from the environment of the file declaring them
+	for secret, variable := range spec.SecretEnvironments {
+		value, ok := env[variable]
+		if !ok {
+			return nil, composeSpecFile{}, errors.Errorf("secret %s: environment variable %s is not set", secret, variable)
+		}
+		l.secrets[secret] = value
+	}
+	for config, content := range spec.ConfigContents {
+		value, err := substituteVariables(content, func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		})
+		if err != nil {
+			return nil, composeSpecFile{}, errors.Wrapf(err, "invalid content of config %s", config)
+		}
+		l.configs[config] = value
+	}
+
+	configDetails := types.ConfigDetails{
+		WorkingDir: workingDir,
+		ConfigFiles: []types.ConfigFile{{
+			Filename: file,
+			Config:   parsedComposeFile,
+		}},
+		Environment: env,
+	}
+
+	// The schema of docker/cli would reject the keys added by the specification
+	config, err := loader.Load(configDetails, withRequiredVariables, func(opts *loader.Options) {
+		opts.SkipValidation = true
+	})
+	if err != nil {
+		return nil, composeSpecFile{}, errors.Wrapf(err, "unable to load %s", file)
+	}
+
+	dir, err := getComposeFileDir([]string{file})
+	if err != nil {
+		return nil, composeSpecFile{}, err
+	}
+	for _, include := range spec.Include {
+		for _, path := range include.Paths {
+			includedConfig, err := l.loadInclude(dir, path, include, chain)
+			if err != nil {
+				return nil, composeSpecFile{}, err
+			}
+			for _, service := range includedConfig.Services {
+				for _, existing := range config.Services {
+					if existing.Name == service.Name {
+						return nil, composeSpecFile{}, errors.Errorf("service %s included from %s conflicts with service %s of %s", service.Name, path, existing.Name, file)
+					}
+				}
+			}
+			config, err = mergeComposeObject(config, includedConfig)
+			if err != nil {
+				return nil, composeSpecFile{}, err
+			}
+		}
+	}
+
+	return config, spec, nil
+}
+
+// loadInclude loads an included file as a project of its own: its relative paths and its .env file
+// are looked up from its own directory, unless the include entry gives a project directory
+func (l *specLoader) loadInclude(dir string, path string, include specInclude, chain []string) (*types.Config, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	workingDir := filepath.Dir(path)
+	if include.ProjectDirectory != "" {
+		workingDir = include.ProjectDirectory
+		if !filepath.IsAbs(workingDir) {
+			workingDir = filepath.Join(dir, workingDir)
+		}
+	}
+
+	var envFiles []string
+	for _, envFile := range include.EnvFiles {
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(dir, envFile)
+		}
+		envFiles = append(envFiles, envFile)
+	}
+	env, err := buildProjectEnvironment(workingDir, envFiles)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build environment variables of %s", path)
+	}
+
+	config, _, err := l.load(path, workingDir, env, chain)
+	return config, err
+}
+
+// filterServicesByProfiles returns the services enabled by the given profiles.
+// A service without profiles is always enabled, and the services an enabled service
+// depends on are enabled as well, whatever their profiles are.
+func filterServicesByProfiles(services types.Services, profiles map[string][]string, enabledProfiles []string) types.Services {
+	enabled := make(map[string]bool)
+	for _, profile := range enabledProfiles {
+		enabled[strings.TrimSpace(profile)] = true
+	}
+
+	isEnabled := func(name string) bool {
+		if len(profiles[name]) == 0 || enabled["*"] {
+			return true
+		}
+		for _, profile := range profiles[name] {
+			if enabled[profile] {
+				return true
+			}
+		}
+		return false
+	}
+
+	byName := make(map[string]types.ServiceConfig, len(services))
+	var queue []string
+	for _, service := range services {
+		byName[service.Name] = service
+		if isEnabled(service.Name) {
+			queue = append(queue, service.Name)
+		}
+	}
+
+	active := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if active[name] {
+			continue
+		}
+		active[name] = true
+		for _, dependency := range byName[name].DependsOn {
+			if _, ok := byName[dependency]; ok && !active[dependency] {
+				if !isEnabled(dependency) {
+					log.Infof("Service %s is enabled because service %s depends on it", dependency, name)
+				}
+				queue = append(queue, dependency)
+			}
+		}
+	}
+
+	var filtered types.Services
+	for _, service := range services {
+		if active[service.Name] {
+			filtered = append(filtered, service)
+		} else {
+			log.Debugf("Service %s is not enabled by profiles %v - ignoring", service.Name, enabledProfiles)
+		}
+	}
+	return filtered
+}
+
+// normalizeComposeSpec takes the Compose Specification keys out of a parsed file
+// and rewrites the service keys whose syntax differs from the one of docker/cli
+func normalizeComposeSpec(config map[string]interface{}) (composeSpecFile, error) {
+	spec := composeSpecFile{
+		Profiles:           make(map[string][]string),
+		DependsOn:          make(map[string]map[string]string),
+		DNSOpts:            make(map[string][]string),
+		Devices:            make(map[string][]specDeviceRequest),
+		SecretEnvironments: make(map[string]string),
+		ConfigContents:     make(map[string]string),
+	}
+
+	if name, ok := config["name"]; ok {
+		spec.Name = fmt.Sprintf("%v", name)
+		delete(config, "name")
+	}
+
+	if include, ok := config["include"]; ok {
+		paths, err := parseSpecInclude(include)
+		if err != nil {
+			return spec, err
+		}
+		spec.Include = paths
+		delete(config, "include")
+	}
+
+	// docker/cli picks the loading rules from the version
+	config["version"] = composeSpecVersion
+
+	for key := range config {
+		if !composeSpecTopLevelKeys[key] && !strings.HasPrefix(key, "x-") {
+			spec.UnknownKeys = append(spec.UnknownKeys, specKey{Key: key})
+			delete(config, key)
+		}
+	}
+
+	// configs defined inline
+	if configs, ok := config["configs"].(map[string]interface{}); ok {
+		for name, c := range configs {
+			cfg, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if content, ok := cfg["content"]; ok {
+				spec.ConfigContents[name] = fmt.Sprintf("%v", content)
+				delete(cfg, "content")
+			}
+		}
+	}
+
+	// secrets created from the value of an environment variable
+	if secrets, ok := config["secrets"].(map[string]interface{}); ok {
+		for name, s := range secrets {
+			secret, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if variable, ok := secret["environment"]; ok {
+				spec.SecretEnvironments[name] = fmt.Sprintf("%v", variable)
+				delete(secret, "environment")
+			}
+		}
+	}
+
+	services, ok := config["services"].(map[string]interface{})
+	if !ok {
+		return spec, nil
+	}
+	for name, s := range services {
+		service, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if profiles, ok := service["profiles"]; ok {
+			list, err := toStringList(profiles)
+			if err != nil {
+				return spec, errors.Wrapf(err, "invalid profiles of service %s", name)
+			}
+			spec.Profiles[name] = list
+			delete(service, "profiles")
+		}
+
+		// dns_opt isn't part of the docker/cli schema
+		if options, ok := service["dns_opt"]; ok {
+			list, err := toStringList(options)
+			if err != nil {
+				return spec, errors.Wrapf(err, "invalid dns_opt of service %s", name)
+			}
+			spec.DNSOpts[name] = list
+			delete(service, "dns_opt")
+		}
+
+		// long syntax of depends_on, docker/cli only knows the list of services
+		if dependsOn, ok := service["depends_on"].(map[string]interface{}); ok {
+			var list []string
+			conditions := make(map[string]string, len(dependsOn))
+			for dependency, d := range dependsOn {
+				list = append(list, dependency)
+				conditions[dependency] = DependsOnServiceStarted
+				if options, ok := d.(map[string]interface{}); ok {
+					if condition, ok := options["condition"].(string); ok {
+						conditions[dependency] = condition
+					}
+				}
+			}
+			service["depends_on"] = list
+			spec.DependsOn[name] = conditions
+		}
+
+		// long syntax of env_file
+		if envFiles, ok := service["env_file"].([]interface{}); ok {
+			for i, envFile := range envFiles {
+				if e, ok := envFile.(map[string]interface{}); ok {
+					envFiles[i] = e["path"]
+				}
+			}
+		}
+
+		normalizeSpecResources(service)
+
+		requests, err := parseSpecDeviceRequests(service)
+		if err != nil {
+			return spec, errors.Wrapf(err, "invalid deploy.resources.reservations.devices of service %s", name)
+		}
+		if len(requests) > 0 {
+			spec.Devices[name] = requests
+		}
+
+		for key := range service {
+			if !composeSpecServiceKeys[key] && !strings.HasPrefix(key, "x-") {
+				spec.UnknownKeys = append(spec.UnknownKeys, specKey{Service: name, Key: key})
+				delete(service, key)
+			}
+		}
+	}
+
+	sort.Slice(spec.UnknownKeys, func(i, j int) bool {
+		if spec.UnknownKeys[i].Service != spec.UnknownKeys[j].Service {
+			return spec.UnknownKeys[i].Service < spec.UnknownKeys[j].Service
+		}
+		return spec.UnknownKeys[i].Key < spec.UnknownKeys[j].Key
+	})
+	return spec, nil
+}
+
+// normalizeSpecResources moves the resources set at the service level
+// (mem_limit, mem_reservation and cpus) to deploy.resources,
+// the values of deploy.resources take precedence
+func normalizeSpecResources(service map[string]interface{}) {
+	keys := []struct {
+		key      string
+		resource string
+		field    string
+	}{
+		{"mem_limit", "limits", "memory"},
+		{"cpus", "limits", "cpus"},
+		{"mem_reservation", "reservations", "memory"},
+	}
+
+	for _, k := range keys {
+		value, ok := service[k.key]
+		if !ok {
+			continue
+		}
+		delete(service, k.key)
+
+		deploy, ok := service["deploy"].(map[string]interface{})
+		if !ok {
+			deploy = make(map[string]interface{})
+			service["deploy"] = deploy
+		}
+		resources, ok := deploy["resources"].(map[string]interface{})
+		if !ok {
+			resources = make(map[string]interface{})
+			deploy["resources"] = resources
+		}
+		resource, ok := resources[k.resource].(map[string]interface{})
+		if !ok {
+			resource = make(map[string]interface{})
+			resources[k.resource] = resource
+		}
+		if _, ok := resource[k.field]; !ok {
+			resource[k.field] = fmt.Sprintf("%v", value)
+		}
+	}
+}
+
+// parseSpecDeviceRequests removes deploy.resources.reservations.devices from a service, docker/cli doesn't know it,
+// and returns its entries
+func parseSpecDeviceRequests(service map[string]interface{}) ([]specDeviceRequest, error) {
+	deploy, _ := service["deploy"].(map[string]interface{})
+	resources, _ := deploy["resources"].(map[string]interface{})
+	reservations, _ := resources["reservations"].(map[string]interface{})
+	devices, ok := reservations["devices"]
+	if !ok {
+		return nil, nil
+	}
+	delete(reservations, "devices")
+
+	entries, ok := devices.([]interface{})
+	if !ok {
+		return nil, errors.New("devices must be a list")
+	}
+	var requests []specDeviceRequest
+	for _, entry := range entries {
+		device, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("%v is not a device", entry)
+		}
+		var request specDeviceRequest
+		var err error
+		if capabilities, ok := device["capabilities"]; ok {
+			if request.Capabilities, err = toStringList(capabilities); err != nil {
+				return nil, errors.Wrap(err, "invalid capabilities")
+			}
+		}
+		if deviceIDs, ok := device["device_ids"]; ok {
+			if request.DeviceIDs, err = toStringList(deviceIDs); err != nil {
+				return nil, errors.Wrap(err, "invalid device_ids")
+			}
+		}
+		if count, ok := device["count"]; ok && fmt.Sprintf("%v", count) != "all" {
+			request.Count = fmt.Sprintf("%v", count)
+			if _, err := strconv.ParseInt(request.Count, 10, 64); err != nil {
+				return nil, errors.Errorf("count must be a number or all, got %v", count)
+			}
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// countGPUs returns the number of GPUs reserved by the device requests of a service.
+// Kubernetes can't reserve all the GPUs of a node nor pick them by ID, these requests are approximated,
+// and the requests of other devices are dropped
+func countGPUs(service string, requests []specDeviceRequest, report *kobject.Report) int64 {
+	const field = "deploy.resources.reservations.devices"
+	var gpus int64
+	for _, request := range requests {
+		isGPU := false
+		for _, capability := range request.Capabilities {
+			if capability == "gpu" {
+				isGPU = true
+			}
+		}
+		if !isGPU {
+			report.Add(kobject.ReportEntry{Service: service, Field: field, Action: kobject.ReportDropped, Reason: fmt.Sprintf("devices with capabilities %v are not supported, only gpu is", request.Capabilities)})
+			report.Warn(service, field, "Ignoring the devices with capabilities %v reserved by service %q, only gpu is supported", request.Capabilities, service)
+			continue
+		}
+
+		switch {
+		case request.Count != "":
+			count, _ := strconv.ParseInt(request.Count, 10, 64)
+			gpus += count
+		case len(request.DeviceIDs) > 0:
+			gpus += int64(len(request.DeviceIDs))
+			report.Add(kobject.ReportEntry{Service: service, Field: field, Action: kobject.ReportApproximated, Reason: fmt.Sprintf("GPUs can't be picked by ID, %d GPUs are requested instead of %s", len(request.DeviceIDs), strings.Join(request.DeviceIDs, ", "))})
+		default:
+			gpus++
+			report.Add(kobject.ReportEntry{Service: service, Field: field, Action: kobject.ReportApproximated, Reason: "all the GPUs of a node can't be requested, 1 GPU is requested"})
+			report.Warn(service, field, "Service %q reserves all the GPUs, 1 GPU is requested instead", service)
+		}
+	}
+	return gpus
+}
+
+// parseSpecInclude returns the entries of the include key,
+// an entry is either a path or an object with a path or a list of paths
+func parseSpecInclude(include interface{}) ([]specInclude, error) {
+	entries, ok := include.([]interface{})
+	if !ok {
+		return nil, errors.New("include must be a list")
+	}
+
+	var includes []specInclude
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			includes = append(includes, specInclude{Paths: []string{e}})
+		case map[string]interface{}:
+			paths, err := toStringList(e["path"])
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid include path")
+			}
+			projectDirectory, _ := e["project_directory"].(string)
+			var envFiles []string
+			if envFile, ok := e["env_file"]; ok {
+				envFiles, err = toStringList(envFile)
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid include env_file")
+				}
+			}
+			includes = append(includes, specInclude{
+				Paths:            paths,
+				ProjectDirectory: projectDirectory,
+				EnvFiles:         envFiles,
+			})
+		default:
+			return nil, fmt.Errorf("invalid include entry %v", entry)
+		}
+	}
+	return includes, nil
+}
+
+// toStringList converts a string or a list of strings
+func toStringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a string", item)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("%v is not a string or a list of strings", value)
+	}
+}
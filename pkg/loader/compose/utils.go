@@ -17,6 +17,7 @@ limitations under the License.
 package compose
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -40,8 +41,44 @@ const (
 	LabelServiceExpose = "kompose.service.expose"
 	// LabelServiceExposeTLSSecret  provides the name of the TLS secret to use with the Kubernetes ingress controller
 	LabelServiceExposeTLSSecret = "kompose.service.expose.tls-secret"
+	// LabelServiceExposeIngressClassName defines the IngressClass of the ingress resource
+	LabelServiceExposeIngressClassName = "kompose.service.expose.ingress-class-name"
+	// LabelServiceExposeAnnotationPrefix prefixes the annotations added to the ingress resource
+	LabelServiceExposeAnnotationPrefix = "kompose.service.expose.annotation."
 	// LabelControllerType defines the type of controller to be created
 	LabelControllerType = "kompose.controller.type"
+	// LabelCronJobSchedule defines the schedule of the CronJob created for the service
+	LabelCronJobSchedule = "kompose.cronjob.schedule"
+	// LabelHPAMinReplicas defines the minimum number of replicas of the HorizontalPodAutoscaler
+	LabelHPAMinReplicas = "kompose.hpa.min"
+	// LabelHPAMaxReplicas defines the maximum number of replicas of the HorizontalPodAutoscaler
+	LabelHPAMaxReplicas = "kompose.hpa.max"
+	// LabelHPACPU defines the target CPU utilization percentage of the HorizontalPodAutoscaler
+	LabelHPACPU = "kompose.hpa.cpu"
+	// LabelHPAMemory defines the target memory utilization percentage of the HorizontalPodAutoscaler
+	LabelHPAMemory = "kompose.hpa.memory"
+	// LabelPDBMinAvailable defines the minAvailable of the PodDisruptionBudget, a number or a percentage of the replicas
+	LabelPDBMinAvailable = "kompose.pdb.min-available"
+	// LabelDevicesResource defines the device plugin resource requested instead of mounting the devices of the service
+	LabelDevicesResource = "kompose.devices.resource"
+	// LabelVolumeSize defines the requested storage size of the PersistentVolumeClaim
+	LabelVolumeSize = "kompose.volume.size"
+	// LabelVolumeSelector defines the label selector of the PersistentVolumeClaim
+	LabelVolumeSelector = "kompose.volume.selector"
+	// LabelVolumeStorageClassName defines the storageClassName of the PersistentVolumeClaim
+	LabelVolumeStorageClassName = "kompose.volume.storage-class-name"
+	// LabelVolumeMode defines the volumeMode of the PersistentVolumeClaim
+	LabelVolumeMode = "kompose.volume.volume-mode"
+	// LabelVolumeAccessMode defines the access mode of the PersistentVolumeClaim
+	LabelVolumeAccessMode = "kompose.volume.access-mode"
+	// LabelSecretName defines the name of the existing Secret referenced by an external secret
+	LabelSecretName = "kompose.secret.name"
+	// LabelSecretKey defines the key of the existing Secret mounted for an external secret
+	LabelSecretKey = "kompose.secret.key"
+	// LabelConfigName defines the name of the existing ConfigMap referenced by an external config
+	LabelConfigName = "kompose.config.name"
+	// LabelConfigKey defines the key of the existing ConfigMap mounted for an external config
+	LabelConfigKey = "kompose.config.key"
 	// LabelImagePullSecret defines a secret name for kubernetes ImagePullSecrets
 	LabelImagePullSecret = "kompose.image-pull-secret"
 	// LabelImagePullPolicy defines Kubernetes PodSpec imagePullPolicy.
@@ -65,8 +102,28 @@ const (
 
 	// ServiceTypeHeadless ...
 	ServiceTypeHeadless = "Headless"
+
+	// DependsOnServiceStarted waits for the dependency to be started
+	DependsOnServiceStarted = "service_started"
+	// DependsOnServiceHealthy waits for the healthcheck of the dependency to pass
+	DependsOnServiceHealthy = "service_healthy"
+	// DependsOnServiceCompletedSuccessfully waits for the dependency to run to completion
+	DependsOnServiceCompletedSuccessfully = "service_completed_successfully"
 )
 
+// loadDependsOn maps the short syntax of depends_on to the condition of each dependency,
+// the short syntax waits for the dependencies to be started
+func loadDependsOn(dependsOn []string) map[string]string {
+	if len(dependsOn) == 0 {
+		return nil
+	}
+	conditions := make(map[string]string, len(dependsOn))
+	for _, dependency := range dependsOn {
+		conditions[normalizeServiceNames(dependency)] = DependsOnServiceStarted
+	}
+	return conditions
+}
+
 // load environment variables from compose file
 func loadEnvVars(envars []string) []kobject.EnvVar {
 	envs := []kobject.EnvVar{}
@@ -163,15 +220,39 @@ func normalizeNetworkNames(netName string) (string, error) {
 	return netval, nil
 }
 
-// ReadFile read data from file or stdin
-func ReadFile(fileName string) ([]byte, error) {
-	if fileName == "-" {
-		if StdinData == nil {
-			data, err := ioutil.ReadAll(os.Stdin)
-			StdinData = data
-			return data, err
+// composeFiles holds the content of the files given to the loader,
+// so that stdin is read only once
+type composeFiles map[string][]byte
+
+// readComposeFiles reads the files given to the loader, "-" is read from stdin
+func readComposeFiles(files []string, stdin io.Reader) (composeFiles, error) {
+	contents := make(composeFiles, len(files))
+	for _, file := range files {
+		if _, ok := contents[file]; ok {
+			continue
+		}
+		var content []byte
+		var err error
+		if file == "-" {
+			if stdin == nil {
+				stdin = os.Stdin
+			}
+			content, err = ioutil.ReadAll(stdin)
+		} else {
+			content, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			return nil, err
 		}
-		return StdinData, nil
+		contents[file] = content
+	}
+	return contents, nil
+}
+
+// read returns the content of a file, the files which weren't given to the loader are read from disk
+func (f composeFiles) read(file string) ([]byte, error) {
+	if content, ok := f[file]; ok {
+		return content, nil
 	}
-	return ioutil.ReadFile(fileName)
+	return ioutil.ReadFile(file)
 }
@@ -19,15 +19,14 @@ package compose
 import (
 	"fmt"
 	"net"
-	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/docker/cli/cli/compose/types"
 	"github.com/docker/cli/opts"
 	"github.com/docker/go-connections/nat"
-	"github.com/docker/libcompose/config"
 	"github.com/docker/libcompose/lookup"
 	"github.com/docker/libcompose/project"
 	"github.com/kubernetes/kompose/pkg/kobject"
@@ -40,44 +39,42 @@ import (
 
 // Parse Docker Compose with libcompose (only supports v1 and v2). Eventually we will
 // switch to using only libcompose once v3 is supported.
-func parseV1V2(files []string) (kobject.KomposeObject, error) {
+func parseV1V2(files []string, contents composeFiles, envFiles []string, report *kobject.Report) (kobject.KomposeObject, error) {
+	// get environment variables
+	env, err := buildEnvironment(files, envFiles)
+	if err != nil {
+		return kobject.KomposeObject{}, errors.Wrap(err, "cannot build environment variables")
+	}
+
 	// Gather the appropriate context for parsing
 	context := &project.Context{}
 	context.ComposeFiles = files
+	context.ResourceLookup = &lookup.FileResourceLookup{}
+	context.EnvironmentLookup = envLookup(env)
 
-	if context.ResourceLookup == nil {
-		context.ResourceLookup = &lookup.FileResourceLookup{}
-	}
-
-	if context.EnvironmentLookup == nil {
-		cwd, err := os.Getwd()
+	// libcompose reads the interpolated files instead of the original ones
+	for _, file := range files {
+		composeBytes, err := prepareV1V2File(file, contents[file], env)
 		if err != nil {
-			return kobject.KomposeObject{}, nil
-		}
-		context.EnvironmentLookup = &lookup.ComposableEnvLookup{
-			Lookups: []config.EnvironmentLookup{
-				&lookup.EnvfileLookup{
-					Path: filepath.Join(cwd, ".env"),
-				},
-				&lookup.OsEnvLookup{},
-			},
+			return kobject.KomposeObject{}, err
 		}
+		context.ComposeBytes = append(context.ComposeBytes, composeBytes)
 	}
 
 	// Load the context and let's start parsing
 	composeObject := project.NewProject(context, nil, nil)
-	err := composeObject.Parse()
+	err = composeObject.Parse()
 	if err != nil {
 		return kobject.KomposeObject{}, errors.Wrap(err, "composeObject.Parse() failed, Failed to load compose file")
 	}
 
-	noSupKeys := checkUnsupportedKey(composeObject)
+	noSupKeys := checkUnsupportedKey(composeObject, report)
 	for _, keyName := range noSupKeys {
-		log.Warningf("Unsupported %s key - ignoring", keyName)
+		report.Warn("", keyName, "Unsupported %s key - ignoring", keyName)
 	}
 
 	// Map the parsed struct to a struct we understand (kobject)
-	komposeObject, err := libComposeToKomposeMapping(composeObject)
+	komposeObject, err := libComposeToKomposeMapping(composeObject, report)
 	if err != nil {
 		return kobject.KomposeObject{}, err
 	}
@@ -167,11 +164,12 @@ func loadPorts(composePorts []string, expose []string) ([]kobject.Ports, error)
 }
 
 // Uses libcompose's APIProject type and converts it to a Kompose object for us to understand
-func libComposeToKomposeMapping(composeObject *project.Project) (kobject.KomposeObject, error) {
+func libComposeToKomposeMapping(composeObject *project.Project, report *kobject.Report) (kobject.KomposeObject, error) {
 	// Initialize what's going to be returned
 	komposeObject := kobject.KomposeObject{
 		ServiceConfigs: make(map[string]kobject.ServiceConfig),
 		LoadedFrom:     "compose",
+		Report:         report,
 	}
 
 	// Here we "clean up" the service configuration so we return something that includes
@@ -189,6 +187,14 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 		serviceConfig.Command = composeServiceConfig.Entrypoint
 		serviceConfig.HostName = composeServiceConfig.Hostname
 		serviceConfig.DomainName = composeServiceConfig.DomainName
+		serviceConfig.DNS = composeServiceConfig.DNS
+		serviceConfig.DNSSearch = composeServiceConfig.DNSSearch
+		serviceConfig.DNSOpts = composeServiceConfig.DNSOpts
+		serviceConfig.ExtraHosts = composeServiceConfig.ExtraHosts
+		serviceConfig.ShmSize = int64(composeServiceConfig.ShmSize)
+		serviceConfig.ReadOnly = composeServiceConfig.ReadOnly
+		serviceConfig.SecurityOpt = composeServiceConfig.SecurityOpt
+		serviceConfig.Devices = composeServiceConfig.Devices
 		serviceConfig.Args = composeServiceConfig.Command
 		serviceConfig.Dockerfile = composeServiceConfig.Build.Dockerfile
 		serviceConfig.BuildArgs = composeServiceConfig.Build.Args
@@ -199,7 +205,7 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 
 		// Validate dockerfile path
 		if filepath.IsAbs(serviceConfig.Dockerfile) {
-			log.Fatalf("%q defined in service %q is an absolute path, it must be a relative path.", serviceConfig.Dockerfile, name)
+			return kobject.KomposeObject{}, fmt.Errorf("%q defined in service %q is an absolute path, it must be a relative path", serviceConfig.Dockerfile, name)
 		}
 
 		// load ports, same as v3, we also load `expose`
@@ -245,12 +251,19 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 		serviceConfig.MemLimit = composeServiceConfig.MemLimit
 		serviceConfig.TmpFs = composeServiceConfig.Tmpfs
 		serviceConfig.StopGracePeriod = composeServiceConfig.StopGracePeriod
+		serviceConfig.DependsOn = loadDependsOn(composeServiceConfig.DependsOn)
 
 		// pretty much same as v3
 		serviceConfig.Restart = composeServiceConfig.Restart
 		if serviceConfig.Restart == "unless-stopped" {
-			log.Warnf("Restart policy 'unless-stopped' in service %s is not supported, convert it to 'always'", name)
+			report.Warn(name, "restart", "Restart policy 'unless-stopped' in service %s is not supported, convert it to 'always'", name)
 			serviceConfig.Restart = "always"
+			report.Add(kobject.ReportEntry{
+				Service: name,
+				Field:   "restart",
+				Action:  kobject.ReportApproximated,
+				Reason:  "restart policy 'unless-stopped' is not supported, converted to 'always'",
+			})
 		}
 
 		if composeServiceConfig.Networks != nil {
@@ -262,7 +275,13 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 							return kobject.KomposeObject{}, errors.Wrap(err, "Error trying to normalize network names")
 						}
 						if nomalizedNetworkName != value.RealName {
-							log.Warnf("Network name in docker-compose has been changed from %q to %q", value.RealName, nomalizedNetworkName)
+							report.Warn(name, "networks", "Network name in docker-compose has been changed from %q to %q", value.RealName, nomalizedNetworkName)
+							report.Add(kobject.ReportEntry{
+								Service: name,
+								Field:   "networks",
+								Action:  kobject.ReportRenamed,
+								Reason:  fmt.Sprintf("network %q is renamed to %q", value.RealName, nomalizedNetworkName),
+							})
 						}
 						serviceConfig.Network = append(serviceConfig.Network, nomalizedNetworkName)
 					}
@@ -279,28 +298,42 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 		komposeObject.ServiceConfigs[normalizeServiceNames(name)] = serviceConfig
 		if normalizeServiceNames(name) != name {
 			log.Infof("Service name in docker-compose has been changed from %q to %q", name, normalizeServiceNames(name))
+			report.Add(kobject.ReportEntry{
+				Service: name,
+				Action:  kobject.ReportRenamed,
+				Reason:  fmt.Sprintf("service %q is renamed to %q", name, normalizeServiceNames(name)),
+			})
 		}
 	}
 
-	// This will handle volume at earlier stage itself, it will resolves problems occurred due to `volumes_from` key
-	handleVolume(&komposeObject)
-
-	return komposeObject, nil
-}
-
-// This function will retrieve volumes for each service, as well as it will parse volume information and store it in Volumes struct
-func handleVolume(komposeObject *kobject.KomposeObject) {
-	for name := range komposeObject.ServiceConfigs {
-		// retrieve volumes of service
-		vols, err := retrieveVolume(name, *komposeObject)
-		if err != nil {
-			errors.Wrap(err, "could not retrieve volume")
+	// This will handle volume at earlier stage itself, it will resolves problems occurred due to `volumes_from` key.
+	// The top-level volumes are mapped the way version 3 ones are, libcompose doesn't read their labels.
+	volumes := make(map[string]types.VolumeConfig, len(composeObject.VolumeConfigs))
+	for name, volume := range composeObject.VolumeConfigs {
+		if volume == nil {
+			volumes[name] = types.VolumeConfig{}
+			continue
+		}
+		// the services mount the volume by the name libcompose gives it, prefixed by the project name unless it is external
+		source := composeObject.Name + "_" + name
+		if volume.External.External {
+			source = name
+			if volume.External.Name != "" {
+				source = volume.External.Name
+			}
+		}
+		volumes[source] = types.VolumeConfig{
+			Name:       volume.External.Name,
+			Driver:     volume.Driver,
+			DriverOpts: volume.DriverOpts,
+			External:   types.External{External: volume.External.External},
 		}
-		// We can't assign value to struct field in map while iterating over it, so temporary variable `temp` is used here
-		var temp = komposeObject.ServiceConfigs[name]
-		temp.Volumes = vols
-		komposeObject.ServiceConfigs[name] = temp
 	}
+	if err := handleV3Volume(&komposeObject, &volumes); err != nil {
+		return kobject.KomposeObject{}, err
+	}
+
+	return komposeObject, nil
 }
 
 func checkLabelsPorts(noOfPort int, labels string, svcName string) error {
@@ -322,7 +355,7 @@ func retrieveVolume(svcName string, komposeObject kobject.KomposeObject) (volume
 				return nil, errors.Wrapf(err, "could not retrieve the volume")
 			}
 			var cVols []kobject.Volumes
-			cVols, err = ParseVols(komposeObject.ServiceConfigs[svcName].VolList, svcName)
+			cVols, err = ParseVols(komposeObject.ServiceConfigs[svcName].VolList, svcName, komposeObject.Report)
 			if err != nil {
 				return nil, errors.Wrapf(err, "error generating current volumes")
 			}
@@ -355,7 +388,7 @@ func retrieveVolume(svcName string, komposeObject kobject.KomposeObject) (volume
 		}
 	} else {
 		// if `volumes-from` is not present
-		volume, err = ParseVols(komposeObject.ServiceConfigs[svcName].VolList, svcName)
+		volume, err = ParseVols(komposeObject.ServiceConfigs[svcName].VolList, svcName, komposeObject.Report)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error generating current volumes")
 		}
@@ -374,13 +407,16 @@ func checkVolDependent(dv kobject.Volumes, volume []kobject.Volumes) bool {
 }
 
 // ParseVols parse volumes
-func ParseVols(volNames []string, svcName string) ([]kobject.Volumes, error) {
+func ParseVols(volNames []string, svcName string, report *kobject.Report) ([]kobject.Volumes, error) {
 	var volumes []kobject.Volumes
 	var err error
 
 	for i, vn := range volNames {
 		var v kobject.Volumes
 		v.VolumeName, v.Host, v.Container, v.Mode, err = transformer.ParseVolume(vn)
+		if mode := vn[strings.LastIndex(vn, ":")+1:]; mode == "z" || mode == "Z" {
+			report.Warn(svcName, "volumes", "Volume mount \"%s\" will be mounted without labeling support. :z or :Z not supported", vn)
+		}
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not parse volume %q: %v", vn, err)
 		}
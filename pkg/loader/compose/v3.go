@@ -17,6 +17,7 @@ limitations under the License.
 package compose
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +27,7 @@ import (
 	libcomposeyaml "github.com/docker/libcompose/yaml"
 
 	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/docker/cli/cli/compose/loader"
 	"github.com/docker/cli/cli/compose/types"
@@ -34,32 +36,17 @@ import (
 
 	"fmt"
 
+	units "github.com/docker/go-units"
 	shlex "github.com/google/shlex"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
-// converts os.Environ() ([]string) to map[string]string
-// based on https://github.com/docker/cli/blob/5dd30732a23bbf14db1c64d084ae4a375f592cfa/cli/command/stack/deploy_composefile.go#L143
-func buildEnvironment() (map[string]string, error) {
-	env := os.Environ()
-	result := make(map[string]string, len(env))
-	for _, s := range env {
-		// if value is empty, s is like "K=", not "K".
-		if !strings.Contains(s, "=") {
-			return result, errors.Errorf("unexpected environment %q", s)
-		}
-		kv := strings.SplitN(s, "=", 2)
-		result[kv[0]] = kv[1]
-	}
-	return result, nil
-}
-
 // The purpose of this is not to deploy, but to be able to parse
 // v3 of Docker Compose into a suitable format. In this case, whatever is returned
 // by docker/cli's ServiceConfig
-func parseV3(files []string) (kobject.KomposeObject, error) {
+func parseV3(files []string, contents composeFiles, envFiles []string, report *kobject.Report) (kobject.KomposeObject, error) {
 	// In order to get V3 parsing to work, we have to go through some preliminary steps
 	// for us to hack up github.com/docker/cli in order to correctly convert to a kobject.KomposeObject
 
@@ -70,7 +57,7 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 	}
 
 	// get environment variables
-	env, err := buildEnvironment()
+	env, err := buildEnvironment(files, envFiles)
 	if err != nil {
 		return kobject.KomposeObject{}, errors.Wrap(err, "cannot build environment variables")
 	}
@@ -78,10 +65,7 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 	var config *types.Config
 	for _, file := range files {
 		// Load and then parse the YAML first!
-		loadedFile, err := ReadFile(file)
-		if err != nil {
-			return kobject.KomposeObject{}, err
-		}
+		loadedFile := contents[file]
 
 		// Parse the Compose File
 		parsedComposeFile, err := loader.ParseYAML(loadedFile)
@@ -89,6 +73,11 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 			return kobject.KomposeObject{}, err
 		}
 
+		// docker/cli doesn't know about extends since version 3
+		if err := resolveExtends(file, parsedComposeFile); err != nil {
+			return kobject.KomposeObject{}, err
+		}
+
 		// Config file
 		configFile := types.ConfigFile{
 			Filename: file,
@@ -106,9 +95,9 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 		// We load it in order to retrieve the parsed output configuration!
 		// This will output a github.com/docker/cli ServiceConfig
 		// Which is similar to our version of ServiceConfig
-		currentConfig, err := loader.Load(configDetails)
+		currentConfig, err := loader.Load(configDetails, withRequiredVariables)
 		if err != nil {
-			return kobject.KomposeObject{}, err
+			return kobject.KomposeObject{}, errors.Wrapf(err, "unable to load %s", file)
 		}
 		if config == nil {
 			config = currentConfig
@@ -120,13 +109,13 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 		}
 	}
 
-	noSupKeys := checkUnsupportedKeyForV3(config)
+	noSupKeys := checkUnsupportedKeyForV3(config, report)
 	for _, keyName := range noSupKeys {
-		log.Warningf("Unsupported %s key - ignoring", keyName)
+		report.Warn("", keyName, "Unsupported %s key - ignoring", keyName)
 	}
 
 	// Finally, we convert the object from docker/cli's ServiceConfig to our appropriate one
-	komposeObject, err := dockerComposeToKomposeMapping(config)
+	komposeObject, err := dockerComposeToKomposeMapping(config, report)
 	if err != nil {
 		return kobject.KomposeObject{}, err
 	}
@@ -134,13 +123,13 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 	return komposeObject, nil
 }
 
-func loadV3Placement(constraints []string) map[string]string {
+func loadV3Placement(name string, constraints []string, report *kobject.Report) map[string]string {
 	placement := make(map[string]string)
 	errMsg := " constraints in placement is not supported, only 'node.hostname', 'engine.labels.operatingsystem' and 'node.labels.xxx' (ex: node.labels.something == anything) is supported as a constraint "
 	for _, j := range constraints {
 		p := strings.Split(j, " == ")
 		if len(p) < 2 {
-			log.Warn(p[0], errMsg)
+			report.Warn(name, "deploy.placement.constraints", "%s%s", p[0], errMsg)
 			continue
 		}
 		if p[0] == "node.hostname" {
@@ -151,7 +140,7 @@ func loadV3Placement(constraints []string) map[string]string {
 			label := strings.TrimPrefix(p[0], "node.labels.")
 			placement[label] = p[1]
 		} else {
-			log.Warn(p[0], errMsg)
+			report.Warn(name, "deploy.placement.constraints", "%s%s", p[0], errMsg)
 		}
 	}
 	return placement
@@ -348,12 +337,13 @@ func parseHealthCheck(composeHealthCheck types.HealthCheckConfig, labels types.L
 	}, nil
 }
 
-func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.KomposeObject, error) {
+func dockerComposeToKomposeMapping(composeObject *types.Config, report *kobject.Report) (kobject.KomposeObject, error) {
 	// Step 1. Initialize what's going to be returned
 	komposeObject := kobject.KomposeObject{
 		ServiceConfigs: make(map[string]kobject.ServiceConfig),
 		LoadedFrom:     "compose",
 		Secrets:        composeObject.Secrets,
+		Report:         report,
 	}
 
 	// Step 2. Parse through the object and convert it to kobject.KomposeObject!
@@ -382,12 +372,29 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		serviceConfig.Labels = composeServiceConfig.Labels
 		serviceConfig.HostName = composeServiceConfig.Hostname
 		serviceConfig.DomainName = composeServiceConfig.DomainName
+		serviceConfig.DNS = composeServiceConfig.DNS
+		serviceConfig.DNSSearch = composeServiceConfig.DNSSearch
+		serviceConfig.ExtraHosts = composeServiceConfig.ExtraHosts
+		serviceConfig.ReadOnly = composeServiceConfig.ReadOnly
+		serviceConfig.SecurityOpt = composeServiceConfig.SecurityOpt
+		serviceConfig.Devices = composeServiceConfig.Devices
 		serviceConfig.Secrets = composeServiceConfig.Secrets
+		serviceConfig.DependsOn = loadDependsOn(composeServiceConfig.DependsOn)
 
 		if composeServiceConfig.StopGracePeriod != nil {
 			serviceConfig.StopGracePeriod = composeServiceConfig.StopGracePeriod.String()
 		}
 
+		if composeServiceConfig.ShmSize != "" {
+			shmSize, err := units.RAMInBytes(composeServiceConfig.ShmSize)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrapf(err, "invalid shm_size of service %s", name)
+			}
+			serviceConfig.ShmSize = shmSize
+		}
+
+		serviceConfig.Sysctls = map[string]string(composeServiceConfig.Sysctls)
+
 		parseV3Network(&composeServiceConfig, &serviceConfig, composeObject)
 
 		if err := parseV3Resources(&composeServiceConfig, &serviceConfig); err != nil {
@@ -423,10 +430,21 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		serviceConfig.Restart = composeServiceConfig.Restart
 		if composeServiceConfig.Deploy.RestartPolicy != nil {
 			serviceConfig.Restart = composeServiceConfig.Deploy.RestartPolicy.Condition
+			serviceConfig.RestartMaxAttempts = composeServiceConfig.Deploy.RestartPolicy.MaxAttempts
 		}
 		if serviceConfig.Restart == "unless-stopped" {
-			log.Warnf("Restart policy 'unless-stopped' in service %s is not supported, convert it to 'always'", name)
 			serviceConfig.Restart = "always"
+			field := "restart"
+			if composeServiceConfig.Deploy.RestartPolicy != nil {
+				field = "deploy.restart_policy.condition"
+			}
+			report.Warn(name, field, "Restart policy 'unless-stopped' in service %s is not supported, convert it to 'always'", name)
+			report.Add(kobject.ReportEntry{
+				Service: name,
+				Field:   field,
+				Action:  kobject.ReportApproximated,
+				Reason:  "restart policy 'unless-stopped' is not supported, converted to 'always'",
+			})
 		}
 
 		// replicas:
@@ -435,7 +453,7 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		}
 
 		// placement:
-		serviceConfig.Placement = loadV3Placement(composeServiceConfig.Deploy.Placement.Constraints)
+		serviceConfig.Placement = loadV3Placement(name, composeServiceConfig.Deploy.Placement.Constraints, report)
 
 		if composeServiceConfig.Deploy.UpdateConfig != nil {
 			serviceConfig.DeployUpdateConfig = *composeServiceConfig.Deploy.UpdateConfig
@@ -474,10 +492,16 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		// Log if the name will been changed
 		if normalizeServiceNames(name) != name {
 			log.Infof("Service name in docker-compose has been changed from %q to %q", name, normalizeServiceNames(name))
+			report.Add(kobject.ReportEntry{
+				Service: name,
+				Action:  kobject.ReportRenamed,
+				Reason:  fmt.Sprintf("service %q is renamed to %q", name, normalizeServiceNames(name)),
+			})
 		}
 
 		serviceConfig.Configs = composeServiceConfig.Configs
 		serviceConfig.ConfigsMetaData = composeObject.Configs
+		serviceConfig.SecretsMetaData = composeObject.Secrets
 		if composeServiceConfig.Deploy.EndpointMode == "vip" {
 			serviceConfig.ServiceType = string(api.ServiceTypeNodePort)
 		}
@@ -485,7 +509,9 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		komposeObject.ServiceConfigs[normalizeServiceNames(name)] = serviceConfig
 	}
 
-	handleV3Volume(&komposeObject, &composeObject.Volumes)
+	if err := handleV3Volume(&komposeObject, &composeObject.Volumes); err != nil {
+		return kobject.KomposeObject{}, err
+	}
 
 	return komposeObject, nil
 }
@@ -592,11 +618,49 @@ func parseKomposeLabels(labels map[string]string, serviceConfig *kobject.Service
 			serviceConfig.NodePortPort = cast.ToInt32(value)
 		case LabelServiceExposeTLSSecret:
 			serviceConfig.ExposeServiceTLS = value
+		case LabelServiceExposeIngressClassName:
+			serviceConfig.ExposeServiceIngressClassName = value
 		case LabelImagePullSecret:
 			serviceConfig.ImagePullSecret = value
 		case LabelImagePullPolicy:
 			serviceConfig.ImagePullPolicy = value
+		case LabelCronJobSchedule:
+			serviceConfig.CronJobSchedule = value
+		case LabelDevicesResource:
+			resource := strings.TrimSpace(value)
+			if errs := validation.IsQualifiedName(resource); len(errs) > 0 || !strings.Contains(resource, "/") {
+				return errors.Errorf("%s must be a resource name like vendor.com/device, got %q", key, value)
+			}
+			serviceConfig.DevicesResource = resource
+		case LabelPDBMinAvailable:
+			minAvailable := strings.TrimSpace(value)
+			if _, err := strconv.ParseUint(strings.TrimSuffix(minAvailable, "%"), 10, 31); err != nil {
+				return errors.Errorf("%s must be a number or a percentage, got %q", key, value)
+			}
+			serviceConfig.PDBMinAvailable = minAvailable
+		case LabelHPAMinReplicas, LabelHPAMaxReplicas, LabelHPACPU, LabelHPAMemory:
+			number, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "%"), 10, 32)
+			if err != nil || number <= 0 {
+				return errors.Errorf("%s must be a positive number, got %q", key, value)
+			}
+			switch key {
+			case LabelHPAMinReplicas:
+				serviceConfig.HPAMinReplicas = int32(number)
+			case LabelHPAMaxReplicas:
+				serviceConfig.HPAMaxReplicas = int32(number)
+			case LabelHPACPU:
+				serviceConfig.HPACPU = int32(number)
+			case LabelHPAMemory:
+				serviceConfig.HPAMemory = int32(number)
+			}
 		default:
+			if strings.HasPrefix(key, LabelServiceExposeAnnotationPrefix) {
+				if serviceConfig.ExposeServiceAnnotations == nil {
+					serviceConfig.ExposeServiceAnnotations = make(map[string]string)
+				}
+				serviceConfig.ExposeServiceAnnotations[strings.TrimPrefix(key, LabelServiceExposeAnnotationPrefix)] = value
+				continue
+			}
 			serviceConfig.Labels[key] = value
 		}
 	}
@@ -605,6 +669,27 @@ func parseKomposeLabels(labels map[string]string, serviceConfig *kobject.Service
 		return errors.New("kompose.service.expose.tls-secret was specified without kompose.service.expose")
 	}
 
+	if strings.Contains(serviceConfig.ExposeServiceTLS, "=") {
+		for _, entry := range strings.Split(serviceConfig.ExposeServiceTLS, ",") {
+			hostSecret := strings.SplitN(entry, "=", 2)
+			if len(hostSecret) != 2 || strings.TrimSpace(hostSecret[0]) == "" || strings.TrimSpace(hostSecret[1]) == "" {
+				return errors.Errorf("invalid kompose.service.expose.tls-secret entry %q, expected host=secret", entry)
+			}
+		}
+	}
+
+	if serviceConfig.ExposeService == "" && (serviceConfig.ExposeServiceIngressClassName != "" || len(serviceConfig.ExposeServiceAnnotations) > 0) {
+		return errors.New("kompose.service.expose.ingress-class-name or kompose.service.expose.annotation.* was specified without kompose.service.expose")
+	}
+
+	if serviceConfig.HPAMaxReplicas == 0 && (serviceConfig.HPAMinReplicas != 0 || serviceConfig.HPACPU != 0 || serviceConfig.HPAMemory != 0) {
+		return errors.New("kompose.hpa.max must be set with the other kompose.hpa.* labels")
+	}
+
+	if serviceConfig.HPAMinReplicas > serviceConfig.HPAMaxReplicas {
+		return errors.Errorf("kompose.hpa.min %d is greater than kompose.hpa.max %d", serviceConfig.HPAMinReplicas, serviceConfig.HPAMaxReplicas)
+	}
+
 	if serviceConfig.ServiceType != string(api.ServiceTypeNodePort) && serviceConfig.NodePortPort != 0 {
 		return errors.New("kompose.service.type must be nodeport when assign node port value")
 	}
@@ -616,44 +701,136 @@ func parseKomposeLabels(labels map[string]string, serviceConfig *kobject.Service
 	return nil
 }
 
-func handleV3Volume(komposeObject *kobject.KomposeObject, volumes *map[string]types.VolumeConfig) {
+// handleV3Volume retrieves the volumes of every service and fills the named volumes from the top-level ones,
+// the version 1 and 2 loader converts its top-level volumes to use it too
+func handleV3Volume(komposeObject *kobject.KomposeObject, volumes *map[string]types.VolumeConfig) error {
+	serviceVolumes := make(map[string][]kobject.Volumes, len(komposeObject.ServiceConfigs))
+	// services mounting each named volume, and whether they all mount it read-only
+	mountedBy := make(map[string]map[string]bool)
+	readOnly := make(map[string]bool)
 	for name := range komposeObject.ServiceConfigs {
 		// retrieve volumes of service
 		vols, err := retrieveVolume(name, *komposeObject)
 		if err != nil {
-			errors.Wrap(err, "could not retrieve vvolume")
+			return errors.Wrap(err, "could not retrieve volume")
 		}
-		for volName, vol := range vols {
-			size, selector := getV3VolumeLabels(vol.VolumeName, volumes)
-			if len(size) > 0 || len(selector) > 0 {
-				// We can't assign value to struct field in map while iterating over it, so temporary variable `temp` is used here
-				var temp = vols[volName]
-				temp.PVCSize = size
-				temp.SelectorValue = selector
-				vols[volName] = temp
+		serviceVolumes[name] = vols
+		for _, vol := range vols {
+			if vol.VolumeName == "" {
+				continue
+			}
+			if mountedBy[vol.VolumeName] == nil {
+				mountedBy[vol.VolumeName] = make(map[string]bool)
+				readOnly[vol.VolumeName] = true
+			}
+			mountedBy[vol.VolumeName][name] = true
+			readOnly[vol.VolumeName] = readOnly[vol.VolumeName] && vol.Mode == "ro"
+		}
+	}
+
+	// top-level volumes are keyed by their name in the compose file,
+	// the volumes of the services use the normalized name
+	komposeObject.NamedVolumes = make(map[string]kobject.NamedVolume, len(*volumes))
+	for key, volume := range *volumes {
+		name := normalizeVolumes(key)
+		namedVolume := kobject.NamedVolume{Name: name}
+		if err := setV3VolumeConfig(&namedVolume, volume, komposeObject.Report); err != nil {
+			return err
+		}
+		for consumer := range mountedBy[name] {
+			namedVolume.Consumers = append(namedVolume.Consumers, consumer)
+		}
+		sort.Strings(namedVolume.Consumers)
+		// a named volume mounted by several services needs to be accessible from several nodes
+		if namedVolume.AccessMode == "" && len(namedVolume.Consumers) > 1 {
+			if readOnly[name] {
+				namedVolume.AccessMode = string(api.ReadOnlyMany)
+			} else {
+				namedVolume.AccessMode = string(api.ReadWriteMany)
 			}
 		}
+		komposeObject.NamedVolumes[name] = namedVolume
+	}
+
+	for name, vols := range serviceVolumes {
+		for i, vol := range vols {
+			namedVolume, ok := komposeObject.NamedVolumes[vol.VolumeName]
+			if !ok {
+				continue
+			}
+			vols[i].PVCSize = namedVolume.PVCSize
+			vols[i].SelectorValue = namedVolume.SelectorValue
+			vols[i].External = namedVolume.External
+			vols[i].ClaimName = namedVolume.ClaimName
+			vols[i].StorageClass = namedVolume.StorageClass
+			vols[i].VolumeMode = namedVolume.VolumeMode
+			vols[i].AccessMode = namedVolume.AccessMode
+			vols[i].NFSServer = namedVolume.NFSServer
+			vols[i].NFSPath = namedVolume.NFSPath
+			vols[i].Shared = len(namedVolume.Consumers) > 1
+		}
 		// We can't assign value to struct field in map while iterating over it, so temporary variable `temp` is used here
 		var temp = komposeObject.ServiceConfigs[name]
 		temp.Volumes = vols
 		komposeObject.ServiceConfigs[name] = temp
 	}
+	return nil
 }
 
-func getV3VolumeLabels(name string, volumes *map[string]types.VolumeConfig) (string, string) {
-	size, selector := "", ""
-
-	if volume, ok := (*volumes)[name]; ok {
-		for key, value := range volume.Labels {
-			if key == "kompose.volume.size" {
-				size = value
-			} else if key == "kompose.volume.selector" {
-				selector = value
+// setV3VolumeConfig fills namedVolume from the labels, external and driver_opts keys of the top-level volume
+func setV3VolumeConfig(namedVolume *kobject.NamedVolume, volume types.VolumeConfig, report *kobject.Report) error {
+	name := namedVolume.Name
+	for key, value := range volume.Labels {
+		switch key {
+		case LabelVolumeSize:
+			namedVolume.PVCSize = value
+		case LabelVolumeSelector:
+			namedVolume.SelectorValue = value
+		case LabelVolumeStorageClassName:
+			namedVolume.StorageClass = value
+		case LabelVolumeMode:
+			if value != string(api.PersistentVolumeFilesystem) && value != string(api.PersistentVolumeBlock) {
+				return errors.Errorf("volume %s: invalid value %q for label %s, expected %s or %s", name, value, key, api.PersistentVolumeFilesystem, api.PersistentVolumeBlock)
+			}
+			namedVolume.VolumeMode = value
+		case LabelVolumeAccessMode:
+			switch api.PersistentVolumeAccessMode(value) {
+			case api.ReadWriteOnce, api.ReadOnlyMany, api.ReadWriteMany, api.ReadWriteOncePod:
+			default:
+				return errors.Errorf("volume %s: invalid value %q for label %s, expected ReadWriteOnce, ReadOnlyMany, ReadWriteMany or ReadWriteOncePod", name, value, key)
 			}
+			namedVolume.AccessMode = value
 		}
 	}
 
-	return size, selector
+	if volume.External.External {
+		namedVolume.External = true
+		namedVolume.ClaimName = volume.Name
+		if namedVolume.ClaimName == "" {
+			namedVolume.ClaimName = name
+		}
+		return nil
+	}
+
+	if volume.DriverOpts["type"] == "nfs" {
+		for _, o := range strings.Split(volume.DriverOpts["o"], ",") {
+			if strings.HasPrefix(o, "addr=") {
+				namedVolume.NFSServer = strings.TrimPrefix(o, "addr=")
+			}
+		}
+		// device is ":/path" or "server:/path"
+		if i := strings.Index(volume.DriverOpts["device"], ":"); i >= 0 {
+			if namedVolume.NFSServer == "" {
+				namedVolume.NFSServer = volume.DriverOpts["device"][:i]
+			}
+			namedVolume.NFSPath = volume.DriverOpts["device"][i+1:]
+		}
+		if namedVolume.NFSServer == "" || namedVolume.NFSPath == "" {
+			report.Warn("", "volumes", "Volume %s: nfs driver_opts need an addr option and a device path, a PersistentVolumeClaim is created instead", name)
+			namedVolume.NFSServer, namedVolume.NFSPath = "", ""
+		}
+	}
+	return nil
 }
 
 func mergeComposeObject(oldCompose *types.Config, newCompose *types.Config) (*types.Config, error) {
@@ -704,6 +881,9 @@ func mergeComposeObject(oldCompose *types.Config, newCompose *types.Config) (*ty
 		if service.Deploy.Mode != "" {
 			tmpOldService.Deploy = service.Deploy
 		}
+		if service.Deploy.Replicas != nil {
+			tmpOldService.Deploy.Replicas = service.Deploy.Replicas
+		}
 		if service.Deploy.Resources.Limits != nil {
 			tmpOldService.Deploy.Resources.Limits = service.Deploy.Resources.Limits
 		}
@@ -810,6 +990,9 @@ func mergeComposeObject(oldCompose *types.Config, newCompose *types.Config) (*ty
 		if len(service.SecurityOpt) != 0 {
 			tmpOldService.SecurityOpt = service.SecurityOpt
 		}
+		if service.ShmSize != "" {
+			tmpOldService.ShmSize = service.ShmSize
+		}
 		if service.StdinOpen != tmpOldService.StdinOpen {
 			tmpOldService.StdinOpen = service.StdinOpen
 		}
@@ -820,6 +1003,15 @@ func mergeComposeObject(oldCompose *types.Config, newCompose *types.Config) (*ty
 			// concat the 2 sets of values
 			tmpOldService.Tmpfs = append(tmpOldService.Tmpfs, service.Tmpfs...)
 		}
+		if len(service.Sysctls) != 0 {
+			// merge the 2 sets of values
+			if tmpOldService.Sysctls == nil {
+				tmpOldService.Sysctls = types.Mapping{}
+			}
+			for k, v := range service.Sysctls {
+				tmpOldService.Sysctls[k] = v
+			}
+		}
 		if service.Tty != tmpOldService.Tty {
 			tmpOldService.Tty = service.Tty
 		}
@@ -889,31 +1081,43 @@ func mergeComposeObject(oldCompose *types.Config, newCompose *types.Config) (*ty
 	return oldCompose, nil
 }
 
-func checkUnsupportedKeyForV3(composeObject *types.Config) []string {
+// checkUnsupportedKeyForV3 returns the unsupported keys of a docker/cli config,
+// each key is returned once however many services use it and every one of them is added to the report
+func checkUnsupportedKeyForV3(composeObject *types.Config, report *kobject.Report) []string {
 	if composeObject == nil {
 		return []string{}
 	}
 
 	var keysFound []string
+	seen := make(map[string]bool)
+	found := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keysFound = append(keysFound, key)
+		}
+	}
 
 	for _, service := range composeObject.Services {
 		for _, tmpConfig := range service.Configs {
-			if tmpConfig.GID != "" {
-				keysFound = append(keysFound, "long syntax config gid")
-			}
 			if tmpConfig.UID != "" {
-				keysFound = append(keysFound, "long syntax config uid")
+				found("long syntax config uid")
+				report.Add(kobject.ReportEntry{Service: service.Name, Field: "configs", Action: kobject.ReportDropped, Reason: fmt.Sprintf("uid of config %s is not supported", tmpConfig.Source)})
 			}
 		}
 
-		if service.CredentialSpec.Registry != "" || service.CredentialSpec.File != "" {
-			keysFound = append(keysFound, "credential_spec")
+		if len(service.Ulimits) > 0 {
+			found("ulimits")
+			var names []string
+			for name := range service.Ulimits {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			report.Add(kobject.ReportEntry{Service: service.Name, Field: "ulimits", Action: kobject.ReportDropped, Reason: fmt.Sprintf("ulimits %s have no Kubernetes equivalent, they are set by the container runtime of the nodes", strings.Join(names, ", "))})
 		}
-	}
 
-	for _, config := range composeObject.Configs {
-		if config.External.External {
-			keysFound = append(keysFound, "external config")
+		if service.CredentialSpec.Registry != "" || service.CredentialSpec.File != "" {
+			found("credential_spec")
+			report.Add(kobject.ReportEntry{Service: service.Name, Field: "credential_spec", Action: kobject.ReportDropped, Reason: "credential_spec key is not supported"})
 		}
 	}
 
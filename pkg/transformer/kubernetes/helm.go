@@ -0,0 +1,472 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// helmLabelsMarker is the label key replaced by the labels of the chart,
+// it sorts before every other label key so that it is never the last one of a JSON object
+const helmLabelsMarker = "!kompose-helm-labels"
+
+var (
+	helmIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	helmNameChars  = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// helmChart turns the objects written to the templates of a chart into Helm templates.
+// Images, replicas, resources, environment values, service types and ingress hosts are lifted
+// into values.yaml, under the name of their service.
+type helmChart struct {
+	name       string
+	jsonFormat bool
+	indent     int
+	values     map[string]interface{}
+	services   []helmService
+}
+
+// helmService is a Service of the chart, listed by NOTES.txt
+type helmService struct {
+	Name string
+	Type string
+}
+
+// helmRef is a field of an object replaced by a reference to values.yaml
+type helmRef struct {
+	marker string
+	// text replaces a scalar field
+	text string
+	// block is rendered with toYaml or toJson to replace a mapping
+	block string
+}
+
+// newHelmChart creates a chart named after its directory
+func newHelmChart(dirName string, jsonFormat bool, indent int) *helmChart {
+	name := dirName
+	if abs, err := filepath.Abs(dirName); err == nil {
+		name = filepath.Base(abs)
+	}
+	name = strings.Trim(helmNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		name = "kompose"
+	}
+
+	return &helmChart{
+		name:       name,
+		jsonFormat: jsonFormat,
+		indent:     indent,
+		values: map[string]interface{}{
+			"nameOverride":     "",
+			"fullnameOverride": "",
+		},
+	}
+}
+
+// template returns the Helm template of an object
+func (c *helmChart) template(obj runtime.Object) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	var refs []helmRef
+	ref := func(text string, block string) string {
+		marker := fmt.Sprintf("__kompose_helm_%d__", len(refs))
+		refs = append(refs, helmRef{marker: marker, text: text, block: block})
+		return marker
+	}
+
+	kind, _ := object["kind"].(string)
+	metadata := nestedMap(object, "metadata")
+	name, _ := metadata["name"].(string)
+	spec := nestedMap(object, "spec")
+
+	switch kind {
+	case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController", "DeploymentConfig":
+		if replicas, ok := spec["replicas"]; ok {
+			c.setValue(replicas, name, "replicaCount")
+			spec["replicas"] = ref(fmt.Sprintf("{{ %s }}", valuesRef(name, "replicaCount")), "")
+		}
+		c.templatePodSpec(nestedMap(spec, "template", "spec"), ref)
+	case "DaemonSet", "Job":
+		c.templatePodSpec(nestedMap(spec, "template", "spec"), ref)
+	case "CronJob":
+		c.templatePodSpec(nestedMap(spec, "jobTemplate", "spec", "template", "spec"), ref)
+	case "Pod":
+		c.templatePodSpec(spec, ref)
+	case "Service":
+		serviceType, ok := spec["type"].(string)
+		if !ok {
+			serviceType = "ClusterIP"
+		}
+		c.setValue(serviceType, name, "service", "type")
+		spec["type"] = ref(fmt.Sprintf("{{ %s }}", valuesRef(name, "service", "type")), "")
+		c.services = append(c.services, helmService{Name: name, Type: valuesRef(name, "service", "type")})
+	case "Ingress":
+		// the hosts of the rules and of the tls entries are templated from the same values
+		var hosts []interface{}
+		hostRef := func(host string) string {
+			index := len(hosts)
+			for i, h := range hosts {
+				if h == host {
+					index = i
+					break
+				}
+			}
+			if index == len(hosts) {
+				hosts = append(hosts, host)
+			}
+			return ref(fmt.Sprintf("{{ index %s %d | quote }}", valuesRef(name, "ingress", "hosts"), index), "")
+		}
+		rules, _ := spec["rules"].([]interface{})
+		for _, r := range rules {
+			rule, _ := r.(map[string]interface{})
+			if host, ok := rule["host"].(string); ok && host != "" {
+				rule["host"] = hostRef(host)
+			}
+		}
+		tls, _ := spec["tls"].([]interface{})
+		for _, t := range tls {
+			entry, _ := t.(map[string]interface{})
+			tlsHosts, _ := entry["hosts"].([]interface{})
+			for i, h := range tlsHosts {
+				if host, ok := h.(string); ok && host != "" {
+					tlsHosts[i] = hostRef(host)
+				}
+			}
+		}
+		if len(hosts) > 0 {
+			c.setValue(hosts, name, "ingress", "hosts")
+		}
+	}
+
+	if metadata != nil {
+		labels := nestedMap(metadata, "labels")
+		if labels == nil {
+			labels = make(map[string]interface{})
+			metadata["labels"] = labels
+		}
+		labels[helmLabelsMarker] = ""
+	}
+
+	if c.jsonFormat {
+		data, err = json.MarshalIndent(object, "", "  ")
+	} else {
+		data, err = marshalWithIndent(object, c.indent)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, helmLabelsMarker) {
+			lines[i] = c.labelsLine(line)
+			continue
+		}
+		for _, r := range refs {
+			if strings.Contains(line, r.marker) {
+				line = c.refLine(line, r)
+			}
+		}
+		lines[i] = line
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// templatePodSpec lifts the images, resources and environment values of the containers of a pod
+func (c *helmChart) templatePodSpec(podSpec map[string]interface{}, ref func(string, string) string) {
+	containers, _ := podSpec["containers"].([]interface{})
+	for _, item := range containers {
+		container, _ := item.(map[string]interface{})
+		name, _ := container["name"].(string)
+		if name == "" {
+			continue
+		}
+
+		if image, ok := container["image"].(string); ok {
+			repository, tag := splitImage(image)
+			c.setValue(repository, name, "image", "repository")
+			c.setValue(tag, name, "image", "tag")
+			container["image"] = ref(fmt.Sprintf(`"{{ %s }}{{ with %s }}:{{ . }}{{ end }}"`,
+				valuesRef(name, "image", "repository"), valuesRef(name, "image", "tag")), "")
+		}
+
+		resources, ok := container["resources"].(map[string]interface{})
+		if !ok {
+			resources = map[string]interface{}{}
+		}
+		c.setValue(resources, name, "resources")
+		container["resources"] = ref("", valuesRef(name, "resources"))
+
+		env, _ := container["env"].([]interface{})
+		for _, e := range env {
+			envVar, _ := e.(map[string]interface{})
+			envName, _ := envVar["name"].(string)
+			if value, ok := envVar["value"].(string); ok && envName != "" {
+				c.setValue(value, name, "env", envName)
+				envVar["value"] = ref(fmt.Sprintf("{{ %s | quote }}", valuesRef(name, "env", envName)), "")
+			}
+		}
+	}
+}
+
+// refLine replaces the marker of a reference in a line of a template
+func (c *helmChart) refLine(line string, r helmRef) string {
+	if r.block != "" {
+		if c.jsonFormat {
+			return strings.Replace(line, `"`+r.marker+`"`, fmt.Sprintf("{{ toJson %s }}", r.block), 1)
+		}
+		// the mapping is indented under its key, which may be the first key of a list item
+		column := len(line) - len(strings.TrimLeft(line, " -"))
+		prefix := strings.TrimRight(line[:strings.Index(line, r.marker)], " ")
+		return fmt.Sprintf("%s {{- toYaml %s | nindent %d }}", prefix, r.block, column+c.indent)
+	}
+
+	for _, quoted := range []string{`"` + r.marker + `"`, `'` + r.marker + `'`, r.marker} {
+		if strings.Contains(line, quoted) {
+			return strings.Replace(line, quoted, r.text, 1)
+		}
+	}
+	return line
+}
+
+// labelsLine replaces the line of the labels marker by the labels of the chart
+func (c *helmChart) labelsLine(line string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	if c.jsonFormat {
+		separator := ""
+		if strings.HasSuffix(strings.TrimSpace(line), ",") {
+			separator = ","
+		}
+		return fmt.Sprintf(`%s{{ include "%s.labels" . | fromYaml | toJson | trimPrefix "{" | trimSuffix "}" }}%s`, indent, c.name, separator)
+	}
+	return fmt.Sprintf(`%s{{- include "%s.labels" . | nindent %d }}`, indent, c.name, len(indent))
+}
+
+// setValue sets a value of values.yaml, the first key is the name of a service
+func (c *helmChart) setValue(value interface{}, keys ...string) {
+	keys[0] = valuesKey(keys[0])
+	values := c.values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			values[key] = next
+		}
+		values = next
+	}
+	values[keys[len(keys)-1]] = value
+}
+
+// write writes Chart.yaml, values.yaml, README.md and the _helpers.tpl and NOTES.txt templates of the chart
+func (c *helmChart) write(dirName string) ([]string, error) {
+	templatesDir := filepath.Join(dirName, "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var values bytes.Buffer
+	values.WriteString(fmt.Sprintf("# Default values for %s, generated by Kompose.\n", c.name))
+	values.WriteString("# The values of each service are grouped under its name.\n")
+	encoder := yaml.NewEncoder(&values)
+	encoder.SetIndent(c.indent)
+	if err := encoder.Encode(c.values); err != nil {
+		return nil, errors.Wrap(err, "unable to marshal values.yaml")
+	}
+
+	files := map[string]string{
+		filepath.Join(dirName, "README.md"):         "This chart was created by Kompose\n",
+		filepath.Join(dirName, "Chart.yaml"):        helmChartTemplate,
+		filepath.Join(dirName, "values.yaml"):       values.String(),
+		filepath.Join(templatesDir, "_helpers.tpl"): helmHelpersTemplate,
+		filepath.Join(templatesDir, "NOTES.txt"):    helmNotesTemplate,
+	}
+	order := []string{
+		filepath.Join(dirName, "README.md"),
+		filepath.Join(dirName, "Chart.yaml"),
+		filepath.Join(dirName, "values.yaml"),
+		filepath.Join(templatesDir, "_helpers.tpl"),
+		filepath.Join(templatesDir, "NOTES.txt"),
+	}
+
+	for _, file := range order {
+		// the files are generated with [[ ]] delimiters, {{ }} are kept for Helm
+		t, err := template.New(filepath.Base(file)).Delims("[[", "]]").Parse(files[file])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to generate %s template", filepath.Base(file))
+		}
+		var data bytes.Buffer
+		if err := t.Execute(&data, c); err != nil {
+			return nil, errors.Wrapf(err, "Failed to generate %s", filepath.Base(file))
+		}
+		if err := ioutil.WriteFile(file, data.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Infof("chart created in %q\n", dirName+string(os.PathSeparator))
+	return order, nil
+}
+
+// Name is the name of the chart, used by the templates of the chart files
+func (c *helmChart) Name() string {
+	return c.name
+}
+
+// Services are the Services of the chart, used by NOTES.txt
+func (c *helmChart) Services() []helmService {
+	return c.services
+}
+
+// valuesKey returns the key of a service in values.yaml, in lower camel case
+func valuesKey(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '.' || r == '_'
+	})
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	key := strings.Join(parts, "")
+	if key == "" {
+		return name
+	}
+	return key
+}
+
+// valuesRef returns the template expression of a value of values.yaml, the first key is the name of a service
+func valuesRef(keys ...string) string {
+	keys = append([]string{valuesKey(keys[0])}, keys[1:]...)
+	for _, key := range keys {
+		if !helmIdentifier.MatchString(key) {
+			quoted := make([]string, len(keys))
+			for i, k := range keys {
+				quoted[i] = fmt.Sprintf("%q", k)
+			}
+			return fmt.Sprintf("(index .Values %s)", strings.Join(quoted, " "))
+		}
+	}
+	return ".Values." + strings.Join(keys, ".")
+}
+
+// splitImage splits an image into its repository and its tag, images given by digest are not split
+func splitImage(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// nestedMap returns the mapping at the path of keys, nil if there is none
+func nestedMap(m map[string]interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		m = next
+	}
+	return m
+}
+
+const helmChartTemplate = `apiVersion: v2
+name: [[ .Name ]]
+description: A Helm chart for Kubernetes generated by Kompose from [[ .Name ]]
+type: application
+version: 0.1.0
+keywords:
+  - [[ .Name ]]
+`
+
+const helmHelpersTemplate = `{{/*
+Expand the name of the chart.
+*/}}
+{{- define "[[ .Name ]].name" -}}
+{{- default .Chart.Name .Values.nameOverride | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{/*
+Create a default fully qualified app name.
+We truncate at 63 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
+*/}}
+{{- define "[[ .Name ]].fullname" -}}
+{{- if .Values.fullnameOverride }}
+{{- .Values.fullnameOverride | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- $name := default .Chart.Name .Values.nameOverride }}
+{{- if contains $name .Release.Name }}
+{{- .Release.Name | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- printf "%s-%s" .Release.Name $name | trunc 63 | trimSuffix "-" }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+Create chart name and version as used by the chart label.
+*/}}
+{{- define "[[ .Name ]].chart" -}}
+{{- printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{/*
+Common labels
+*/}}
+{{- define "[[ .Name ]].labels" -}}
+helm.sh/chart: {{ include "[[ .Name ]].chart" . }}
+app.kubernetes.io/name: {{ include "[[ .Name ]].name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- if .Chart.AppVersion }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+{{- end }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+{{- end }}
+`
+
+const helmNotesTemplate = `{{ include "[[ .Name ]].fullname" . }} was generated by Kompose and installed as release {{ .Release.Name }} in namespace {{ .Release.Namespace }}.
+[[- if .Services ]]
+
+Services:
+[[- range .Services ]]
+  - [[ .Name ]] ({{ [[ .Type ]] }})
+[[- end ]]
+
+Get their addresses with:
+  kubectl get --namespace {{ .Release.Namespace }} services
+[[- end ]]
+`
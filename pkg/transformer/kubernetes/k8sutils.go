@@ -21,14 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -41,76 +42,16 @@ import (
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	api "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-/**
- * Generate Helm Chart configuration
- */
-func generateHelm(dirName string) error {
-	type ChartDetails struct {
-		Name string
-	}
-
-	details := ChartDetails{dirName}
-	manifestDir := dirName + string(os.PathSeparator) + "templates"
-	dir, err := os.Open(dirName)
-
-	/* Setup the initial directories/files */
-	if err == nil {
-		_ = dir.Close()
-	}
-
-	if err != nil {
-		err = os.Mkdir(dirName, 0755)
-		if err != nil {
-			return err
-		}
-
-		err = os.Mkdir(manifestDir, 0755)
-		if err != nil {
-			return err
-		}
-	}
-
-	/* Create the readme file */
-	readme := "This chart was created by Kompose\n"
-	err = ioutil.WriteFile(dirName+string(os.PathSeparator)+"README.md", []byte(readme), 0644)
-	if err != nil {
-		return err
-	}
-
-	/* Create the Chart.yaml file */
-	chart := `name: {{.Name}}
-description: A generated Helm Chart for {{.Name}} from Skippbox Kompose
-version: 0.0.1
-apiVersion: v1
-keywords:
-  - {{.Name}}
-sources:
-home:
-`
-
-	t, err := template.New("ChartTmpl").Parse(chart)
-	if err != nil {
-		return errors.Wrap(err, "Failed to generate Chart.yaml template, template.New failed")
-	}
-	var chartData bytes.Buffer
-	_ = t.Execute(&chartData, details)
-
-	err = ioutil.WriteFile(dirName+string(os.PathSeparator)+"Chart.yaml", chartData.Bytes(), 0644)
-	if err != nil {
-		return err
-	}
-
-	log.Infof("chart created in %q\n", dirName+string(os.PathSeparator))
-	return nil
-}
-
 // Check if given path is a directory
 func isDir(name string) (bool, error) {
 	// Open file to get stat later
@@ -159,8 +100,9 @@ func objectToRaw(object runtime.Object) runtime.RawExtension {
 	return r
 }
 
-// PrintList will take the data converted and decide on the commandline attributes given
-func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
+// PrintList will take the data converted and decide on the commandline attributes given,
+// it returns the paths of the files written
+func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) ([]string, error) {
 	var f *os.File
 	dirName := getDirName(opt)
 	log.Debugf("Target Dir: %s", dirName)
@@ -168,14 +110,14 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 	// Create a directory if "out" ends with "/" and does not exist.
 	if !transformer.Exists(opt.OutFile) && strings.HasSuffix(opt.OutFile, "/") {
 		if err := os.MkdirAll(opt.OutFile, os.ModePerm); err != nil {
-			return errors.Wrap(err, "failed to create a directory")
+			return nil, errors.Wrap(err, "failed to create a directory")
 		}
 	}
 
 	// Check if output file is a directory
 	isDirVal, err := isDir(opt.OutFile)
 	if err != nil {
-		return errors.Wrap(err, "isDir failed")
+		return nil, errors.Wrap(err, "isDir failed")
 	}
 	if opt.CreateChart {
 		isDirVal = true
@@ -183,7 +125,7 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 	if !isDirVal {
 		f, err = transformer.CreateOutFile(opt.OutFile)
 		if err != nil {
-			return errors.Wrap(err, "transformer.CreateOutFile failed")
+			return nil, errors.Wrap(err, "transformer.CreateOutFile failed")
 		}
 		if len(opt.OutFile) != 0 {
 			log.Printf("Kubernetes file %q created", opt.OutFile)
@@ -192,6 +134,10 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 	}
 
 	var files []string
+	var chart *helmChart
+	if opt.CreateChart {
+		chart = newHelmChart(dirName, opt.GenerateJSON, opt.YAMLIndent)
+	}
 
 	// if asked to print to stdout or to put in single file
 	// we will create a list
@@ -201,7 +147,7 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 		for _, object := range objects {
 			versionedObject, err := convertToVersion(object, metav1.GroupVersion{})
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			list.Items = append(list.Items, objectToRaw(versionedObject))
@@ -212,17 +158,19 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 		list.APIVersion = "v1"
 		convertedList, err := convertToVersion(list, listVersion)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		data, err := marshal(convertedList, opt.GenerateJSON, opt.YAMLIndent)
 		if err != nil {
-			return fmt.Errorf("error in marshalling the List: %v", err)
+			return nil, fmt.Errorf("error in marshalling the List: %v", err)
 		}
-		printVal, err := transformer.Print("", dirName, "", data, opt.ToStdout, opt.GenerateJSON, f, opt.Provider)
+		_, err = transformer.Print("", dirName, "", data, opt.ToStdout, opt.GenerateJSON, f, opt.Provider)
 		if err != nil {
-			return errors.Wrap(err, "transformer.Print failed")
+			return nil, errors.Wrap(err, "transformer.Print failed")
+		}
+		if f != nil {
+			files = append(files, opt.OutFile)
 		}
-		files = append(files, printVal)
 	} else {
 		finalDirName := dirName
 		if opt.CreateChart {
@@ -230,7 +178,7 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 		}
 
 		if err := os.MkdirAll(finalDirName, 0755); err != nil {
-			return err
+			return nil, err
 		}
 
 		var file string
@@ -238,50 +186,59 @@ func PrintList(objects []runtime.Object, opt kobject.ConvertOptions) error {
 		for _, v := range objects {
 			versionedObject, err := convertToVersion(v, metav1.GroupVersion{})
 			if err != nil {
-				return err
+				return nil, err
 			}
-			data, err := marshal(versionedObject, opt.GenerateJSON, opt.YAMLIndent)
-			if err != nil {
-				return err
-			}
-
-			var typeMeta metav1.TypeMeta
-			var objectMeta metav1.ObjectMeta
-
-			if us, ok := v.(*unstructured.Unstructured); ok {
-				typeMeta = metav1.TypeMeta{
-					Kind:       us.GetKind(),
-					APIVersion: us.GetAPIVersion(),
-				}
-				objectMeta = metav1.ObjectMeta{
-					Name: us.GetName(),
-				}
+			var data []byte
+			if chart != nil {
+				data, err = chart.template(versionedObject)
 			} else {
-				val := reflect.ValueOf(v).Elem()
-				// Use reflect to access TypeMeta struct inside runtime.Object.
-				// cast it to correct type - metav1.TypeMeta
-				typeMeta = val.FieldByName("TypeMeta").Interface().(metav1.TypeMeta)
-
-				// Use reflect to access ObjectMeta struct inside runtime.Object.
-				// cast it to correct type - api.ObjectMeta
-				objectMeta = val.FieldByName("ObjectMeta").Interface().(metav1.ObjectMeta)
+				data, err = marshal(versionedObject, opt.GenerateJSON, opt.YAMLIndent)
+			}
+			if err != nil {
+				return nil, err
 			}
 
+			typeMeta, objectMeta := getObjectMeta(v)
 			file, err = transformer.Print(objectMeta.Name, finalDirName, strings.ToLower(typeMeta.Kind), data, opt.ToStdout, opt.GenerateJSON, f, opt.Provider)
 			if err != nil {
-				return errors.Wrap(err, "transformer.Print failed")
+				return nil, errors.Wrap(err, "transformer.Print failed")
 			}
 
-			files = append(files, file)
+			if file != "" {
+				files = append(files, file)
+			}
 		}
 	}
-	if opt.CreateChart {
-		err = generateHelm(dirName)
+	if chart != nil {
+		chartFiles, err := chart.write(dirName)
 		if err != nil {
-			return errors.Wrap(err, "generateHelm failed")
+			return nil, errors.Wrap(err, "failed to generate the Helm chart")
 		}
+		files = append(files, chartFiles...)
 	}
-	return nil
+	return files, nil
+}
+
+// getObjectMeta returns the TypeMeta and the ObjectMeta of an object
+func getObjectMeta(v runtime.Object) (metav1.TypeMeta, metav1.ObjectMeta) {
+	if us, ok := v.(*unstructured.Unstructured); ok {
+		return metav1.TypeMeta{
+			Kind:       us.GetKind(),
+			APIVersion: us.GetAPIVersion(),
+		}, metav1.ObjectMeta{
+			Name: us.GetName(),
+		}
+	}
+
+	val := reflect.ValueOf(v).Elem()
+	// Use reflect to access TypeMeta struct inside runtime.Object.
+	// cast it to correct type - metav1.TypeMeta
+	typeMeta := val.FieldByName("TypeMeta").Interface().(metav1.TypeMeta)
+
+	// Use reflect to access ObjectMeta struct inside runtime.Object.
+	// cast it to correct type - api.ObjectMeta
+	objectMeta := val.FieldByName("ObjectMeta").Interface().(metav1.ObjectMeta)
+	return typeMeta, objectMeta
 }
 
 // marshal object runtime.Object and return byte array
@@ -346,6 +303,12 @@ func convertToVersion(obj runtime.Object, groupVersion metav1.GroupVersion) (run
 		return obj, nil
 	}
 
+	// the GroupVersions of a kind have the same schema for the fields kompose generates,
+	// switching the version of the object is enough to convert it
+	if !groupVersion.Empty() {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		obj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Group: groupVersion.Group, Version: groupVersion.Version, Kind: kind})
+	}
 	return obj, nil
 
 	//var version metav1.GroupVersion
@@ -449,6 +412,12 @@ func (k *Kubernetes) UpdateKubernetesObjectsMultipleContainers(name string, serv
 	fillTemplate := func(template *api.PodTemplateSpec) error {
 		template.ObjectMeta.Labels = transformer.ConfigLabelsWithNetwork(name, service.Network)
 		template.Spec = podSpec.Get()
+		for key, value := range podSpec.Annotations() {
+			if template.ObjectMeta.Annotations == nil {
+				template.ObjectMeta.Annotations = map[string]string{}
+			}
+			template.ObjectMeta.Annotations[key] = value
+		}
 		return nil
 	}
 
@@ -497,6 +466,21 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		volumesMount = append(volumesMount, TmpVolumesMount...)
 	}
 
+	// Configure shm_size
+	if service.ShmSize > 0 {
+		shmVolumesMount, shmVolumes := k.ConfigShmSize(name, service)
+		volumes = append(volumes, shmVolumes...)
+		volumesMount = append(volumesMount, shmVolumesMount...)
+	}
+
+	// Configure devices
+	devicesMount, devices := k.ConfigDevices(name, service)
+	volumes = append(volumes, devices...)
+	volumesMount = append(volumesMount, devicesMount...)
+
+	// StatefulSet claims its storage through volumeClaimTemplates
+	volumes, pvc = ConfigStatefulSetClaims(*objects, volumes, pvc)
+
 	if pvc != nil {
 		// Looping on the slice pvc instead of `*objects = append(*objects, pvc...)`
 		// because the type of objects and pvc is different, but when doing append
@@ -541,14 +525,14 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			probe := api.Probe{}
 
 			if len(service.HealthChecks.Liveness.Test) > 0 {
-				probe.Handler = api.Handler{
+				probe.ProbeHandler = api.ProbeHandler{
 					Exec: &api.ExecAction{
 						Command: service.HealthChecks.Liveness.Test,
 					},
 				}
 			} else if !reflect.ValueOf(service.HealthChecks.Liveness.HTTPPath).IsZero() &&
 				!reflect.ValueOf(service.HealthChecks.Liveness.HTTPPort).IsZero() {
-				probe.Handler = api.Handler{
+				probe.ProbeHandler = api.ProbeHandler{
 					HTTPGet: &api.HTTPGetAction{
 						Path: service.HealthChecks.Liveness.HTTPPath,
 						Port: intstr.FromInt(int(service.HealthChecks.Liveness.HTTPPort)),
@@ -572,11 +556,19 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		if !reflect.DeepEqual(service.HealthChecks.Readiness, kobject.HealthCheck{}) {
 			probeHealthCheckReadiness := api.Probe{}
 			if len(service.HealthChecks.Readiness.Test) > 0 {
-				probeHealthCheckReadiness.Handler = api.Handler{
+				probeHealthCheckReadiness.ProbeHandler = api.ProbeHandler{
 					Exec: &api.ExecAction{
 						Command: service.HealthChecks.Readiness.Test,
 					},
 				}
+			} else if !reflect.ValueOf(service.HealthChecks.Readiness.HTTPPath).IsZero() &&
+				!reflect.ValueOf(service.HealthChecks.Readiness.HTTPPort).IsZero() {
+				probeHealthCheckReadiness.ProbeHandler = api.ProbeHandler{
+					HTTPGet: &api.HTTPGetAction{
+						Path: service.HealthChecks.Readiness.HTTPPath,
+						Port: intstr.FromInt(int(service.HealthChecks.Readiness.HTTPPort)),
+					},
+				}
 			} else {
 				return errors.New("Health check must contain a command")
 			}
@@ -596,11 +588,12 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		if service.StopGracePeriod != "" {
 			template.Spec.TerminationGracePeriodSeconds, err = DurationStrToSecondsInt(service.StopGracePeriod)
 			if err != nil {
-				log.Warningf("Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
+				k.Report.Warn(name, "stop_grace_period", "Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
 			}
 		}
 
 		TranslatePodResource(&service, template)
+		addResourceLimits(&template.Spec.Containers[0], ConfigDeviceResources(service, opt))
 
 		// Configure resource reservations
 		podSecurityContext := &api.PodSecurityContext{}
@@ -610,7 +603,7 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			if service.Pid == "host" {
 				// podSecurityContext.HostPID = true
 			} else {
-				log.Warningf("Ignoring PID key for service \"%v\". Invalid value \"%v\".", name, service.Pid)
+				k.Report.Warn(name, "pid", "Ignoring PID key for service \"%v\". Invalid value \"%v\".", name, service.Pid)
 			}
 		}
 
@@ -619,15 +612,21 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			podSecurityContext.SupplementalGroups = service.GroupAdd
 		}
 
+		podSecurityContext.Sysctls = ConfigSysctls(name, service, k.Report)
+		podSecurityContext.FSGroup = ConfigFSGroup(name, service, k.Report)
+
 		// Setup security context
 		securityContext := &api.SecurityContext{}
 		if service.Privileged {
 			securityContext.Privileged = &service.Privileged
 		}
+		if service.ReadOnly {
+			securityContext.ReadOnlyRootFilesystem = &service.ReadOnly
+		}
 		if service.User != "" {
 			uid, err := strconv.ParseInt(service.User, 10, 64)
 			if err != nil {
-				log.Warn("Ignoring user directive. User to be specified as a UID (numeric).")
+				k.Report.Warn(name, "user", "Ignoring user directive. User to be specified as a UID (numeric).")
 			} else {
 				securityContext.RunAsUser = &uid
 			}
@@ -638,6 +637,14 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			securityContext.Capabilities = capabilities
 		}
 
+		// set seccomp, SELinux, no-new-privileges and AppArmor from security_opt
+		if appArmorProfile := ConfigSecurityOpts(name, service, securityContext, k.Report); appArmorProfile != "" {
+			if template.ObjectMeta.Annotations == nil {
+				template.ObjectMeta.Annotations = map[string]string{}
+			}
+			template.ObjectMeta.Annotations[AppArmorAnnotationPrefix+template.Spec.Containers[0].Name] = appArmorProfile
+		}
+
 		// update template only if securityContext is not empty
 		if *securityContext != (api.SecurityContext{}) {
 			template.Spec.Containers[0].SecurityContext = securityContext
@@ -670,6 +677,13 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			template.Spec.Subdomain = service.DomainName
 		}
 
+		// Configure extra_hosts and dns settings
+		template.Spec.HostAliases = GetHostAliases(name, service.ExtraHosts, k.Report)
+		if policy, dnsConfig := GetDNSConfig(name, service, k.Report); dnsConfig != nil {
+			template.Spec.DNSPolicy = policy
+			template.Spec.DNSConfig = dnsConfig
+		}
+
 		return nil
 	}
 
@@ -696,6 +710,196 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 	return nil
 }
 
+// ConfigFSGroup returns the fsGroup of the pod from the gid of the secrets and configs of the service,
+// so that their files belong to that group. Their owner can't be changed, the uid is ignored.
+func ConfigFSGroup(name string, service kobject.ServiceConfig, report *kobject.Report) *int64 {
+	type file struct{ field, source, uid, gid string }
+	var files []file
+	for _, secret := range service.Secrets {
+		if secret.UID != "" && secret.UID != "0" {
+			report.Warn(name, "secrets", "Ignore uid %s of secret %s for service %s, the files of the secrets are owned by root", secret.UID, secret.Source, name)
+		}
+		files = append(files, file{"secrets", secret.Source, secret.UID, secret.GID})
+	}
+	// the uid of the configs is already reported by the loader
+	for _, config := range service.Configs {
+		files = append(files, file{"configs", config.Source, config.UID, config.GID})
+	}
+
+	var fsGroup *int64
+	for _, f := range files {
+		if f.gid == "" {
+			continue
+		}
+		gid, err := strconv.ParseInt(f.gid, 10, 64)
+		if err != nil {
+			report.Warn(name, f.field, "Ignore invalid gid %s of %s for service %s", f.gid, f.source, name)
+			continue
+		}
+		if fsGroup != nil && *fsGroup != gid {
+			report.Warn(name, f.field, "Ignore gid %d of %s for service %s, the fsGroup of the pod is already %d", gid, f.source, name, *fsGroup)
+			continue
+		}
+		fsGroup = &gid
+	}
+	return fsGroup
+}
+
+// ConfigStatefulSetClaims moves the given claims into the volumeClaimTemplates of the StatefulSet
+// found in objects, so that every replica gets its own storage. The pod volumes pointing to
+// those claims are removed, the volume mounts refer to the templates by name.
+// volumes and claims are returned unchanged if there is no StatefulSet.
+func ConfigStatefulSetClaims(objects []runtime.Object, volumes []api.Volume, pvc []*api.PersistentVolumeClaim) ([]api.Volume, []*api.PersistentVolumeClaim) {
+	var ss *appsv1.StatefulSet
+	for _, obj := range objects {
+		if t, ok := obj.(*appsv1.StatefulSet); ok {
+			ss = t
+		}
+	}
+	if ss == nil || len(pvc) == 0 {
+		return volumes, pvc
+	}
+
+	claims := make(map[string]bool)
+	for _, p := range pvc {
+		ss.Spec.VolumeClaimTemplates = append(ss.Spec.VolumeClaimTemplates, api.PersistentVolumeClaim{
+			ObjectMeta: p.ObjectMeta,
+			Spec:       p.Spec,
+		})
+		claims[p.Name] = true
+	}
+
+	var remain []api.Volume
+	for _, v := range volumes {
+		if v.PersistentVolumeClaim != nil && claims[v.PersistentVolumeClaim.ClaimName] {
+			continue
+		}
+		remain = append(remain, v)
+	}
+	return remain, nil
+}
+
+// WaitForDependencyImage is the default image of the init containers waiting for the dependencies of a service
+const WaitForDependencyImage = "busybox:1.36"
+
+// ConfigInitContainers creates an init container for each service the given service depends on.
+// The init container blocks until the Service of the dependency answers on its first TCP port,
+// which only happens once a pod of the dependency is ready.
+func ConfigInitContainers(name string, service kobject.ServiceConfig, komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) []api.Container {
+	var dependencies []string
+	for dependency := range service.DependsOn {
+		dependencies = append(dependencies, dependency)
+	}
+	sort.Strings(dependencies)
+
+	image := opt.WaitForDependenciesImage
+	if image == "" {
+		image = WaitForDependencyImage
+	}
+
+	var containers []api.Container
+	for _, dependency := range dependencies {
+		dependencyService, ok := komposeObject.ServiceConfigs[dependency]
+		if !ok {
+			komposeObject.Report.Warn(name, "depends_on", "Service %s depends on undefined service %s - ignoring", name, dependency)
+			continue
+		}
+
+		svcName := dependency
+		if opt.MultipleContainerMode {
+			if groupID, ok := dependencyService.Labels[compose.LabelServiceGroup]; ok {
+				// containers of the same pod start together
+				if groupID == service.Labels[compose.LabelServiceGroup] {
+					continue
+				}
+				svcName = groupID
+			}
+		}
+
+		if service.DependsOn[dependency] == compose.DependsOnServiceCompletedSuccessfully {
+			komposeObject.Report.Warn(name, "depends_on", "Condition %s of service %s dependency %s is not supported, wait for %s to answer instead", compose.DependsOnServiceCompletedSuccessfully, name, dependency, dependency)
+		}
+
+		port := firstTCPServicePort(dependencyService)
+		if port == 0 {
+			komposeObject.Report.Warn(name, "depends_on", "Service %s depends on %s which has no TCP port, init container won't be created", name, dependency)
+			continue
+		}
+
+		containers = append(containers, api.Container{
+			Name:  "wait-for-" + dependency,
+			Image: image,
+			Command: []string{
+				"sh",
+				"-c",
+				fmt.Sprintf("until nc -z -w 2 %s %d; do echo waiting for %s; sleep 2; done", svcName, port, svcName),
+			},
+		})
+	}
+	return containers
+}
+
+// AddInitContainers appends the given init containers to the pod template of the controllers
+func (k *Kubernetes) AddInitContainers(objects []runtime.Object, containers []api.Container) error {
+	if len(containers) == 0 {
+		return nil
+	}
+	addInitContainers := func(template *api.PodTemplateSpec) error {
+		template.Spec.InitContainers = append(template.Spec.InitContainers, containers...)
+		return nil
+	}
+	for _, obj := range objects {
+		err := k.UpdateController(obj, addInitContainers, func(meta *metav1.ObjectMeta) {})
+		if err != nil {
+			return errors.Wrap(err, "k.UpdateController failed")
+		}
+	}
+	return nil
+}
+
+// firstTCPServicePort returns the first TCP port of the Service created for the given service
+func firstTCPServicePort(service kobject.ServiceConfig) int32 {
+	for _, port := range service.Port {
+		if port.Protocol != "" && port.Protocol != api.ProtocolTCP {
+			continue
+		}
+		if port.HostPort != 0 {
+			return port.HostPort
+		}
+		return port.ContainerPort
+	}
+	return 0
+}
+
+// ConfigHealthyDependencies turns the healthcheck of the services other services depend on
+// with the service_healthy condition into a readiness probe, so their Service only answers
+// once the healthcheck passes
+func ConfigHealthyDependencies(komposeObject *kobject.KomposeObject) {
+	for name, service := range komposeObject.ServiceConfigs {
+		for dependency, condition := range service.DependsOn {
+			if condition != compose.DependsOnServiceHealthy {
+				continue
+			}
+			dependencyService, ok := komposeObject.ServiceConfigs[dependency]
+			if !ok || hasHealthCheck(dependencyService.HealthChecks.Readiness) {
+				continue
+			}
+			liveness := dependencyService.HealthChecks.Liveness
+			if !hasHealthCheck(liveness) {
+				komposeObject.Report.Warn(name, "depends_on", "Service %s waits for %s to be healthy, but %s has no healthcheck test", name, dependency, dependency)
+				continue
+			}
+			dependencyService.HealthChecks.Readiness = liveness
+			komposeObject.ServiceConfigs[dependency] = dependencyService
+		}
+	}
+}
+
+// hasHealthCheck checks if a healthcheck runs a command or an HTTP request
+func hasHealthCheck(healthCheck kobject.HealthCheck) bool {
+	return len(healthCheck.Test) > 0 || (healthCheck.HTTPPath != "" && healthCheck.HTTPPort != 0)
+}
+
 // KomposeObjectToServiceConfigGroupMapping returns the service config group by name
 func KomposeObjectToServiceConfigGroupMapping(komposeObject kobject.KomposeObject) map[string]kobject.ServiceConfigGroup {
 	serviceConfigGroup := make(map[string]kobject.ServiceConfigGroup)
@@ -775,6 +979,200 @@ func GetRestartPolicy(name, restart string) (api.RestartPolicy, error) {
 	}
 }
 
+// GetHostAliases converts the extra_hosts of a service, "host:ip" or "host=ip" entries,
+// to the host aliases of its pods, grouping the hostnames by IP
+func GetHostAliases(name string, extraHosts []string, report *kobject.Report) []api.HostAlias {
+	var hostAliases []api.HostAlias
+	index := make(map[string]int)
+	for _, extraHost := range extraHosts {
+		sep := strings.Index(extraHost, "=")
+		if sep < 0 {
+			sep = strings.Index(extraHost, ":")
+		}
+		if sep <= 0 || net.ParseIP(extraHost[sep+1:]) == nil {
+			report.Warn(name, "extra_hosts", "Ignoring extra host %q of service %q, it must be a hostname and an IP address", extraHost, name)
+			continue
+		}
+		host, ip := extraHost[:sep], extraHost[sep+1:]
+		if i, ok := index[ip]; ok {
+			hostAliases[i].Hostnames = append(hostAliases[i].Hostnames, host)
+			continue
+		}
+		index[ip] = len(hostAliases)
+		hostAliases = append(hostAliases, api.HostAlias{IP: ip, Hostnames: []string{host}})
+	}
+	return hostAliases
+}
+
+// GetDNSConfig converts the dns, dns_search and dns_opt of a service to the DNS policy and config of its pods.
+// The nameservers of a service replace the cluster DNS, like they replace the DNS of the Docker network
+func GetDNSConfig(name string, service kobject.ServiceConfig, report *kobject.Report) (api.DNSPolicy, *api.PodDNSConfig) {
+	if len(service.DNS) == 0 && len(service.DNSSearch) == 0 && len(service.DNSOpts) == 0 {
+		return "", nil
+	}
+
+	dnsConfig := &api.PodDNSConfig{
+		Nameservers: service.DNS,
+		Searches:    service.DNSSearch,
+	}
+	for _, option := range service.DNSOpts {
+		parts := strings.SplitN(option, ":", 2)
+		dnsOption := api.PodDNSConfigOption{Name: parts[0]}
+		if len(parts) == 2 {
+			dnsOption.Value = &parts[1]
+		}
+		dnsConfig.Options = append(dnsConfig.Options, dnsOption)
+	}
+
+	if len(service.DNS) == 0 {
+		return "", dnsConfig
+	}
+	report.Warn(name, "dns", "Service %q sets its own DNS servers, its pods won't resolve the names of the other services through the cluster DNS", name)
+	return api.DNSNone, dnsConfig
+}
+
+// safeSysctls are the sysctls the kubelet allows by default, the other ones must be allowed
+// on the nodes with --allowed-unsafe-sysctls
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.ping_group_range":           true,
+}
+
+// ConfigSysctls converts the sysctls of a service to the sysctls of its pods, sorted by name
+func ConfigSysctls(name string, service kobject.ServiceConfig, report *kobject.Report) []api.Sysctl {
+	var names, unsafe []string
+	for sysctl := range service.Sysctls {
+		names = append(names, sysctl)
+		if !safeSysctls[sysctl] {
+			unsafe = append(unsafe, sysctl)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(unsafe)
+
+	var sysctls []api.Sysctl
+	for _, sysctl := range names {
+		sysctls = append(sysctls, api.Sysctl{Name: sysctl, Value: service.Sysctls[sysctl]})
+	}
+	if len(unsafe) > 0 {
+		report.Warn(name, "sysctls", "Service %q uses the unsafe sysctls %s, its pods won't start unless the kubelet allows them with --allowed-unsafe-sysctls", name, strings.Join(unsafe, ", "))
+	}
+	return sysctls
+}
+
+// DefaultGPUResource is the extended resource requested for the GPUs when --gpu-resource isn't given
+const DefaultGPUResource = "nvidia.com/gpu"
+
+// ConfigDeviceResources returns the extended resources requested by a service, its GPUs
+// and the device plugin resource replacing its devices, or nil
+func ConfigDeviceResources(service kobject.ServiceConfig, opt kobject.ConvertOptions) api.ResourceList {
+	resources := api.ResourceList{}
+	if service.GPUs > 0 {
+		gpuResource := opt.GPUResource
+		if gpuResource == "" {
+			gpuResource = DefaultGPUResource
+		}
+		resources[api.ResourceName(gpuResource)] = *resource.NewQuantity(service.GPUs, resource.DecimalSI)
+	}
+	if service.DevicesResource != "" && len(service.Devices) > 0 {
+		resources[api.ResourceName(service.DevicesResource)] = *resource.NewQuantity(int64(len(service.Devices)), resource.DecimalSI)
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return resources
+}
+
+// addResourceLimits adds resources to the limits of a container, the extended resources
+// are only set as limits, their requests default to them
+func addResourceLimits(container *api.Container, resources api.ResourceList) {
+	if len(resources) == 0 {
+		return
+	}
+	limits := api.ResourceList{}
+	for name, quantity := range container.Resources.Limits {
+		limits[name] = quantity
+	}
+	for name, quantity := range resources {
+		limits[name] = quantity
+	}
+	container.Resources.Limits = limits
+}
+
+// AppArmorAnnotationPrefix is the prefix of the pod annotations setting the AppArmor profile of a container,
+// the name of the container follows it
+const AppArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// ConfigSecurityOpts sets the seccomp profile, the SELinux options and the privilege escalation
+// of a container from the security_opt of its service. It returns the AppArmor profile of the container,
+// in the format of the AppArmor annotation, or an empty string.
+func ConfigSecurityOpts(name string, service kobject.ServiceConfig, securityContext *api.SecurityContext, report *kobject.Report) string {
+	var appArmorProfile string
+	for _, securityOpt := range service.SecurityOpt {
+		// docker accepts both key=value and key:value
+		sep := strings.IndexAny(securityOpt, "=:")
+		key, value := securityOpt, ""
+		if sep >= 0 {
+			key, value = securityOpt[:sep], securityOpt[sep+1:]
+		}
+
+		switch key {
+		case "no-new-privileges":
+			if value == "" || value == "true" {
+				allowPrivilegeEscalation := false
+				securityContext.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+			}
+		case "seccomp":
+			switch value {
+			case "unconfined":
+				securityContext.SeccompProfile = &api.SeccompProfile{Type: api.SeccompProfileTypeUnconfined}
+			case "builtin", "default", "runtime/default":
+				securityContext.SeccompProfile = &api.SeccompProfile{Type: api.SeccompProfileTypeRuntimeDefault}
+			default:
+				profile := filepath.Base(value)
+				securityContext.SeccompProfile = &api.SeccompProfile{Type: api.SeccompProfileTypeLocalhost, LocalhostProfile: &profile}
+				report.Warn(name, "security_opt", "Service %q uses the seccomp profile %s, it must be installed on the nodes as %s in the seccomp directory of the kubelet", name, value, profile)
+			}
+		case "apparmor":
+			switch value {
+			case "unconfined":
+				appArmorProfile = "unconfined"
+			case "docker-default", "runtime/default":
+				appArmorProfile = "runtime/default"
+			default:
+				appArmorProfile = "localhost/" + value
+			}
+		case "label":
+			sep := strings.IndexAny(value, "=:")
+			if sep < 0 {
+				report.Warn(name, "security_opt", "Ignoring security_opt %q of service %q, disabling the SELinux labeling isn't supported", securityOpt, name)
+				continue
+			}
+			if securityContext.SELinuxOptions == nil {
+				securityContext.SELinuxOptions = &api.SELinuxOptions{}
+			}
+			switch option := value[sep+1:]; value[:sep] {
+			case "user":
+				securityContext.SELinuxOptions.User = option
+			case "role":
+				securityContext.SELinuxOptions.Role = option
+			case "type":
+				securityContext.SELinuxOptions.Type = option
+			case "level":
+				securityContext.SELinuxOptions.Level = option
+			default:
+				report.Warn(name, "security_opt", "Ignoring security_opt %q of service %q, unknown SELinux label", securityOpt, name)
+			}
+		default:
+			report.Warn(name, "security_opt", "Ignoring security_opt %q of service %q, it isn't supported", securityOpt, name)
+		}
+	}
+	return appArmorProfile
+}
+
 // SortServicesFirst - the objects that we get can be in any order this keeps services first
 // according to best practice kubernetes services should be created first
 // http://kubernetes.io/docs/user-guide/config-best-practices/
@@ -793,6 +1191,31 @@ func (k *Kubernetes) SortServicesFirst(objs *[]runtime.Object) {
 	*objs = ret
 }
 
+// GetNamespace returns the namespace of the objects: the one given by the options,
+// or the one derived from the project name unless the namespace is explicitly set to empty
+func GetNamespace(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) string {
+	if opt.Namespace != "" || opt.IsNamespaceFlag {
+		return opt.Namespace
+	}
+	return FormatNamespaceName(komposeObject.Name)
+}
+
+// SetNamespace sets the namespace of the namespaced objects
+func SetNamespace(objs []runtime.Object, namespace string) {
+	if namespace == "" {
+		return
+	}
+	for _, obj := range objs {
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "Namespace", "Project":
+			continue
+		}
+		if meta, ok := obj.(metav1.Object); ok {
+			meta.SetNamespace(namespace)
+		}
+	}
+}
+
 // RemoveDupObjects remove objects that are dups...eg. configmaps from env.
 // since we know for sure that the duplication can only happens on ConfigMap, so
 // this code will looks like this for now.
@@ -867,6 +1290,62 @@ func GetContentFromFile(file string) (string, error) {
 	return string(fileBytes), nil
 }
 
+// ingressAnnotations returns the annotations of the ingress of a service,
+// the annotations given by the kompose.service.expose.annotation.* labels are added to the kompose ones
+func ingressAnnotations(service kobject.ServiceConfig) map[string]string {
+	annotations := transformer.ConfigAnnotations(service)
+	for key, value := range service.ExposeServiceAnnotations {
+		annotations[key] = value
+	}
+	return annotations
+}
+
+// ingressPath returns the path of an ingress rule and its type: the paths with wildcards or regular expressions
+// are implementation specific, the other ones are prefixes, "/" if no path is given
+func ingressPath(path string) (string, networkingv1.PathType) {
+	if path == "" {
+		return "/", networkingv1.PathTypePrefix
+	}
+	if strings.ContainsAny(path, `*?^$()[]{}|+\`) {
+		return path, networkingv1.PathTypeImplementationSpecific
+	}
+	return path, networkingv1.PathTypePrefix
+}
+
+// ingressTLS returns the TLS configuration of an ingress from the kompose.service.expose.tls-secret label:
+// "true" enables TLS with the default certificate, a comma separated list of host=secret
+// gives the secret of each host, and any other value is the secret of all the hosts
+func ingressTLS(value string, hosts []string) []networkingv1.IngressTLS {
+	switch {
+	case value == "":
+		return nil
+	case value == "true":
+		return []networkingv1.IngressTLS{{Hosts: hosts}}
+	case strings.Contains(value, "="):
+		var tls []networkingv1.IngressTLS
+		for _, entry := range strings.Split(value, ",") {
+			hostSecret := strings.SplitN(entry, "=", 2)
+			tls = append(tls, networkingv1.IngressTLS{
+				Hosts:      []string{strings.TrimSpace(hostSecret[0])},
+				SecretName: strings.TrimSpace(hostSecret[1]),
+			})
+		}
+		return tls
+	default:
+		return []networkingv1.IngressTLS{{Hosts: hosts, SecretName: value}}
+	}
+}
+
+// FormatNamespaceName formats a project name as a namespace name, which is a DNS-1123 label
+func FormatNamespaceName(name string) string {
+	namespace := strings.ToLower(name)
+	namespace = regexp.MustCompile(`[^a-z0-9-]+`).ReplaceAllString(namespace, "-")
+	if len(namespace) > validation.DNS1123LabelMaxLength {
+		namespace = namespace[:validation.DNS1123LabelMaxLength]
+	}
+	return strings.Trim(namespace, "-")
+}
+
 // FormatEnvName format env name
 func FormatEnvName(name string) string {
 	envName := strings.Trim(name, "./")
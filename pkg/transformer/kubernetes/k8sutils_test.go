@@ -17,10 +17,12 @@ limitations under the License.
 package kubernetes
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/kubernetes/kompose/pkg/kobject"
@@ -28,6 +30,9 @@ import (
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 /*
@@ -487,3 +492,202 @@ func TestDurationStrToSecondsInt(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintKustomize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-kustomize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	deployment := func(name string, replicas int32) runtime.Object {
+		return &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: name, Image: name}}},
+				},
+			},
+		}
+	}
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+	}
+
+	objects := []runtime.Object{deployment("web", 1), service}
+	overlays := map[string][]runtime.Object{
+		"prod":    {deployment("web", 3), deployment("cache", 1)},
+		"staging": {deployment("web", 1), service},
+	}
+	files, err := PrintKustomize(objects, overlays, kobject.ConvertOptions{OutFile: dir, YAMLIndent: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedFiles := []string{
+		"base/web-deployment.yaml",
+		"base/web-service.yaml",
+		"base/kustomization.yaml",
+		"overlays/prod/web-deployment-patch.yaml",
+		"overlays/prod/cache-deployment.yaml",
+		"overlays/prod/web-service-patch.yaml",
+		"overlays/prod/kustomization.yaml",
+		"overlays/staging/kustomization.yaml",
+	}
+	for i := range expectedFiles {
+		expectedFiles[i] = filepath.Join(dir, expectedFiles[i])
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("Expected files %v, got %v", expectedFiles, files)
+	}
+
+	testCases := map[string]struct {
+		file     string
+		expected string
+	}{
+		"Base kustomization": {"base/kustomization.yaml", `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - web-deployment.yaml
+  - web-service.yaml
+`},
+		"Overlay kustomization": {"overlays/prod/kustomization.yaml", `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+patches:
+  - path: web-deployment-patch.yaml
+  - path: web-service-patch.yaml
+resources:
+  - ../../base
+  - cache-deployment.yaml
+`},
+		"Changed object": {"overlays/prod/web-deployment-patch.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`},
+		"Removed object": {"overlays/prod/web-service-patch.yaml", `$patch: delete
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`},
+		"Unchanged overlay": {"overlays/staging/kustomization.yaml", `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - ../../base
+`},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		data, err := ioutil.ReadFile(filepath.Join(dir, test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("Expected %s to be:\n%s\ngot:\n%s", test.file, test.expected, data)
+		}
+	}
+}
+
+func TestPrintListChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	chartDir := filepath.Join(dir, "my_app")
+
+	replicas := int32(2)
+	objects := []runtime.Object{
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web-app", Labels: map[string]string{"io.kompose.service": "web-app"}},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:  "web-app",
+						Image: "registry:5000/nginx:1.19",
+						Env:   []corev1.EnvVar{{Name: "MODE", Value: "dev"}},
+					}}},
+				},
+			},
+		},
+		&corev1.Service{
+			TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web-app"},
+		},
+		&networkingv1.Ingress{
+			TypeMeta:   metav1.TypeMeta{Kind: "Ingress", APIVersion: "networking.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web-app"},
+			Spec: networkingv1.IngressSpec{
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"example.com", "www.example.com"}, SecretName: "web-app-tls"}},
+				Rules: []networkingv1.IngressRule{{Host: "example.com"}, {Host: "www.example.com"}},
+			},
+		},
+	}
+
+	files, err := PrintList(objects, kobject.ConvertOptions{CreateChart: true, OutFile: chartDir, YAMLIndent: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 8 {
+		t.Errorf("Expected 3 templates and 5 chart files, got %v", files)
+	}
+
+	testCases := map[string]struct {
+		file     string
+		expected []string
+	}{
+		"Chart": {"Chart.yaml", []string{"apiVersion: v2\n", "name: my-app\n"}},
+		"Values": {"values.yaml", []string{`webApp:
+  env:
+    MODE: dev
+  image:
+    repository: registry:5000/nginx
+    tag: "1.19"
+  ingress:
+    hosts:
+      - example.com
+      - www.example.com
+  replicaCount: 2
+  resources: {}
+  service:
+    type: ClusterIP
+`}},
+		"Helpers": {"templates/_helpers.tpl", []string{`{{- define "my-app.labels" -}}`, `{{- define "my-app.fullname" -}}`}},
+		"Notes":   {"templates/NOTES.txt", []string{"  - web-app ({{ .Values.webApp.service.type }})"}},
+		"Deployment": {"templates/web-app-deployment.yaml", []string{
+			"  labels:\n    {{- include \"my-app.labels\" . | nindent 4 }}\n    io.kompose.service: web-app\n",
+			"  replicas: {{ .Values.webApp.replicaCount }}\n",
+			"value: {{ .Values.webApp.env.MODE | quote }}\n",
+			`image: "{{ .Values.webApp.image.repository }}{{ with .Values.webApp.image.tag }}:{{ . }}{{ end }}"`,
+			"          resources: {{- toYaml .Values.webApp.resources | nindent 12 }}\n",
+		}},
+		"Service": {"templates/web-app-service.yaml", []string{"  type: {{ .Values.webApp.service.type }}\n"}},
+		"Ingress": {"templates/web-app-ingress.yaml", []string{
+			"  - host: {{ index .Values.webApp.ingress.hosts 0 | quote }}\n",
+			"  - host: {{ index .Values.webApp.ingress.hosts 1 | quote }}\n",
+			"    - hosts:\n        - {{ index .Values.webApp.ingress.hosts 0 | quote }}\n        - {{ index .Values.webApp.ingress.hosts 1 | quote }}\n",
+		}},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		data, err := ioutil.ReadFile(filepath.Join(chartDir, test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(string(data), expected) {
+				t.Errorf("Expected %s to contain:\n%s\ngot:\n%s", test.file, expected, data)
+			}
+		}
+	}
+}
@@ -38,9 +38,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cast"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	api "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -51,6 +54,8 @@ import (
 type Kubernetes struct {
 	// the user provided options from the command line
 	Opt kobject.ConvertOptions
+	// Report records the warnings of the conversion, Transform sets it to the report of the compose object
+	Report *kobject.Report
 }
 
 // TIMEOUT is how long we'll wait for the termination of kubernetes resource to be successful
@@ -67,24 +72,29 @@ const (
 	DaemonSetController = "daemonset"
 	// ReplicationController is controller type for  ReplicationController
 	ReplicationController = "replicationcontroller"
+	// StatefulSetController is controller type for StatefulSet
+	StatefulSetController = "statefulset"
+	// JobController is controller type for Job, or CronJob if a schedule is given
+	JobController = "job"
 )
 
 // CheckUnsupportedKey checks if given komposeObject contains
 // keys that are not supported by this transformer.
 // list of all unsupported keys are stored in unsupportedKey variable
 // returns list of TODO: ....
+// every service using one of them is added to the report of komposeObject
 func (k *Kubernetes) CheckUnsupportedKey(komposeObject *kobject.KomposeObject, unsupportedKey map[string]bool) []string {
 	// collect all keys found in project
 	var keysFound []string
 
-	for _, serviceConfig := range komposeObject.ServiceConfigs {
+	for name, serviceConfig := range komposeObject.ServiceConfigs {
 		// this reflection is used in check for empty arrays
 		val := reflect.ValueOf(serviceConfig)
 		s := structs.New(serviceConfig)
 
 		for _, f := range s.Fields() {
 			// Check if given key is among unsupported keys, and skip it if we already saw this key
-			if alreadySaw, ok := unsupportedKey[f.Name()]; ok && !alreadySaw {
+			if alreadySaw, ok := unsupportedKey[f.Name()]; ok {
 				if f.IsExported() && !f.IsZero() {
 					// IsZero returns false for empty array/slice ([])
 					// this check if field is Slice, and then it checks its size
@@ -96,8 +106,16 @@ func (k *Kubernetes) CheckUnsupportedKey(komposeObject *kobject.KomposeObject, u
 					}
 					//get tag from kobject service configure
 					tag := f.Tag(komposeObject.LoadedFrom)
-					keysFound = append(keysFound, tag)
-					unsupportedKey[f.Name()] = true
+					komposeObject.Report.Add(kobject.ReportEntry{
+						Service: name,
+						Field:   tag,
+						Action:  kobject.ReportDropped,
+						Reason:  fmt.Sprintf("%s key is not supported by the provider", tag),
+					})
+					if !alreadySaw {
+						keysFound = append(keysFound, tag)
+						unsupportedKey[f.Name()] = true
+					}
 				}
 			}
 		}
@@ -141,24 +159,32 @@ func (k *Kubernetes) InitPodSpecWithConfigMap(name string, image string, service
 			// short syntax, = /<source>
 			target = "/" + value.Source
 		}
-		subPath := filepath.Base(target)
 
-		volSource := api.ConfigMapVolumeSource{}
-		volSource.Name = cmVolName
-		key, err := service.GetConfigMapKeyFromMeta(value.Source)
+		cmName, key, err := configMapReference(value.Source, service)
 		if err != nil {
-			log.Warnf("cannot parse config %s , %s", value.Source, err.Error())
-			// mostly it's external
+			k.Report.Warn(name, "configs", "cannot parse config %s , %s", value.Source, err.Error())
 			continue
 		}
-		volSource.Items = []api.KeyToPath{{
-			Key:  key,
-			Path: subPath,
-		}}
+		volSource := api.ConfigMapVolumeSource{}
+		volSource.Name = cmName
+
+		// the whole ConfigMap is mounted at the target when no key is given
+		var subPath string
+		if key != "" {
+			subPath = filepath.Base(target)
+			volSource.Items = []api.KeyToPath{{
+				Key:  key,
+				Path: subPath,
+			}}
+		}
 
 		if value.Mode != nil {
 			tmpMode := int32(*value.Mode)
-			volSource.DefaultMode = &tmpMode
+			if len(volSource.Items) > 0 {
+				volSource.Items[0].Mode = &tmpMode
+			} else {
+				volSource.DefaultMode = &tmpMode
+			}
 		}
 
 		cmVol := api.Volume{
@@ -188,6 +214,26 @@ func (k *Kubernetes) InitPodSpecWithConfigMap(name string, image string, service
 	return pod
 }
 
+// configMapReference returns the name of the ConfigMap and its key mounted for the given config of the service.
+// External configs refer to an existing ConfigMap, named by the kompose.config.name label or the config name,
+// the whole ConfigMap is mounted unless the kompose.config.key label gives the key.
+func configMapReference(source string, service kobject.ServiceConfig) (string, string, error) {
+	config, ok := service.ConfigsMetaData[source]
+	if !ok || !config.External.External {
+		key, err := service.GetConfigMapKeyFromMeta(source)
+		return FormatFileName(source), key, err
+	}
+
+	name := config.Name
+	if label, ok := config.Labels[compose.LabelConfigName]; ok {
+		name = label
+	}
+	if name == "" {
+		name = source
+	}
+	return name, config.Labels[compose.LabelConfigKey], nil
+}
+
 // InitSvc initializes Kubernetes Service object
 func (k *Kubernetes) InitSvc(name string, service kobject.ServiceConfig) *api.Service {
 	svc := &api.Service{
@@ -206,11 +252,24 @@ func (k *Kubernetes) InitSvc(name string, service kobject.ServiceConfig) *api.Se
 	return svc
 }
 
+// InitNamespace initializes Kubernetes Namespace object
+func (k *Kubernetes) InitNamespace(name string) *api.Namespace {
+	return &api.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+}
+
 // InitConfigMapForEnv initializes a ConfigMap object
-func (k *Kubernetes) InitConfigMapForEnv(name string, service kobject.ServiceConfig, opt kobject.ConvertOptions, envFile string) *api.ConfigMap {
+func (k *Kubernetes) InitConfigMapForEnv(name string, service kobject.ServiceConfig, opt kobject.ConvertOptions, envFile string) (*api.ConfigMap, error) {
 	envs, err := GetEnvsFromFile(envFile, opt)
 	if err != nil {
-		log.Fatalf("Unable to retrieve env file: %s", err)
+		return nil, errors.Wrap(err, "Unable to retrieve env file")
 	}
 
 	// Remove root pathing
@@ -230,7 +289,7 @@ func (k *Kubernetes) InitConfigMapForEnv(name string, service kobject.ServiceCon
 		Data: envs,
 	}
 
-	return configMap
+	return configMap, nil
 }
 
 // IntiConfigMapFromFileOrDir will create a configmap from dir or file
@@ -275,7 +334,10 @@ func (k *Kubernetes) IntiConfigMapFromFileOrDir(name, cmName, filePath string, s
 
 	case mode.IsRegular():
 		// do file stuff
-		configMap = k.InitConfigMapFromFile(name, service, filePath)
+		configMap, err = k.InitConfigMapFromFile(name, service, filePath)
+		if err != nil {
+			return nil, err
+		}
 		configMap.Name = cmName
 		configMap.Annotations = map[string]string{
 			"use-subpath": "true",
@@ -297,11 +359,26 @@ func useSubPathMount(cm *api.ConfigMap) bool {
 	return true
 }
 
+// InitConfigMapFromContent initializes the ConfigMap of a config defined inline
+func (k *Kubernetes) InitConfigMapFromContent(name string, configName string, content string) *api.ConfigMap {
+	return &api.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   FormatFileName(configName),
+			Labels: transformer.ConfigLabels(name),
+		},
+		Data: map[string]string{configName: content},
+	}
+}
+
 //InitConfigMapFromFile initializes a ConfigMap object
-func (k *Kubernetes) InitConfigMapFromFile(name string, service kobject.ServiceConfig, fileName string) *api.ConfigMap {
+func (k *Kubernetes) InitConfigMapFromFile(name string, service kobject.ServiceConfig, fileName string) (*api.ConfigMap, error) {
 	content, err := GetContentFromFile(fileName)
 	if err != nil {
-		log.Fatalf("Unable to retrieve file: %s", err)
+		return nil, errors.Wrap(err, "Unable to retrieve file")
 	}
 
 	dataMap := make(map[string]string)
@@ -324,7 +401,7 @@ func (k *Kubernetes) InitConfigMapFromFile(name string, service kobject.ServiceC
 		},
 		Data: dataMap,
 	}
-	return configMap
+	return configMap, nil
 }
 
 // InitD initializes Kubernetes Deployment object
@@ -383,6 +460,109 @@ func (k *Kubernetes) InitD(name string, service kobject.ServiceConfig, replicas
 	return dc
 }
 
+// InitRC initializes Kubernetes ReplicationController object
+func (k *Kubernetes) InitRC(name string, service kobject.ServiceConfig, replicas int) *api.ReplicationController {
+	var podSpec api.PodSpec
+	if len(service.Configs) > 0 {
+		podSpec = k.InitPodSpecWithConfigMap(name, service.Image, service)
+	} else {
+		podSpec = k.InitPodSpec(name, service.Image, service.ImagePullSecret)
+	}
+
+	rp := int32(replicas)
+
+	rc := &api.ReplicationController{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ReplicationController",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigAllLabels(name, &service),
+		},
+		Spec: api.ReplicationControllerSpec{
+			Replicas: &rp,
+			Selector: transformer.ConfigLabels(name),
+			Template: &api.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      transformer.ConfigLabels(name),
+					Annotations: transformer.ConfigAnnotations(service),
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+	return rc
+}
+
+// InitJob initializes Kubernetes Job object
+func (k *Kubernetes) InitJob(name string, service kobject.ServiceConfig) *batchv1.Job {
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigAllLabels(name, &service),
+		},
+		Spec: k.initJobSpec(name, service),
+	}
+	return job
+}
+
+// InitCronJob initializes Kubernetes CronJob object
+func (k *Kubernetes) InitCronJob(name string, service kobject.ServiceConfig) *batchv1.CronJob {
+	cronJob := &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigAllLabels(name, &service),
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: service.CronJobSchedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: k.initJobSpec(name, service),
+			},
+		},
+	}
+	return cronJob
+}
+
+// initJobSpec creates the job specification shared by Job and CronJob
+// restart: no never retries a failed pod, while restart: on-failure retries
+// up to deploy.restart_policy.max_attempts times
+func (k *Kubernetes) initJobSpec(name string, service kobject.ServiceConfig) batchv1.JobSpec {
+	var podSpec api.PodSpec
+	if len(service.Configs) > 0 {
+		podSpec = k.InitPodSpecWithConfigMap(name, service.Image, service)
+	} else {
+		podSpec = k.InitPodSpec(name, service.Image, service.ImagePullSecret)
+	}
+
+	spec := batchv1.JobSpec{
+		Template: api.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      transformer.ConfigLabels(name),
+				Annotations: transformer.ConfigAnnotations(service),
+			},
+			Spec: podSpec,
+		},
+	}
+
+	if service.RestartMaxAttempts != nil {
+		backoffLimit := int32(*service.RestartMaxAttempts)
+		spec.BackoffLimit = &backoffLimit
+	} else if service.Restart == "no" {
+		backoffLimit := int32(0)
+		spec.BackoffLimit = &backoffLimit
+	}
+	return spec
+}
+
 // InitDS initializes Kubernetes DaemonSet object
 func (k *Kubernetes) InitDS(name string, service kobject.ServiceConfig) *appsv1.DaemonSet {
 	ds := &appsv1.DaemonSet{
@@ -403,9 +583,119 @@ func (k *Kubernetes) InitDS(name string, service kobject.ServiceConfig) *appsv1.
 	return ds
 }
 
-func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, port int32) *networkingv1beta1.Ingress {
+// InitSS initializes Kubernetes StatefulSet object
+// the volumeClaimTemplates are filled later, from the claims generated by ConfigVolumes
+func (k *Kubernetes) InitSS(name string, service kobject.ServiceConfig, replicas int) *appsv1.StatefulSet {
+	var podSpec api.PodSpec
+	if len(service.Configs) > 0 {
+		podSpec = k.InitPodSpecWithConfigMap(name, service.Image, service)
+	} else {
+		podSpec = k.InitPodSpec(name, service.Image, service.ImagePullSecret)
+	}
+
+	rp := int32(replicas)
+
+	ss := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigAllLabels(name, &service),
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &rp,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: transformer.ConfigLabels(name),
+			},
+			// the governing service is the headless service created with the same name
+			ServiceName: name,
+			Template: api.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: transformer.ConfigAnnotations(service),
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+	ss.Spec.Template.Labels = transformer.ConfigLabels(name)
+
+	return ss
+}
+
+// initIngress initializes the networking.k8s.io/v1 Ingress of a service,
+// or the extensions/v1beta1 one when the target Kubernetes version is older than 1.19
+func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, port int32, opt kobject.ConvertOptions) runtime.Object {
+	if apiVersion, _ := APIVersion("Ingress", opt.KubeVersion); apiVersion == "extensions/v1beta1" {
+		return k.initIngressV1beta1(name, service, port)
+	}
+
+	hosts := regexp.MustCompile("[ ,]*,[ ,]*").Split(service.ExposeService, -1)
+
+	ingress := &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      transformer.ConfigLabels(name),
+			Annotations: ingressAnnotations(service),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: make([]networkingv1.IngressRule, len(hosts)),
+		},
+	}
+	if service.ExposeServiceIngressClassName != "" {
+		ingress.Spec.IngressClassName = &service.ExposeServiceIngressClassName
+	}
+
+	var tlsHosts []string
+	for i, host := range hosts {
+		host, p := transformer.ParseIngressPath(host)
+		path, pathType := ingressPath(p)
+		ingress.Spec.Rules[i] = networkingv1.IngressRule{
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Path:     path,
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: name,
+									Port: networkingv1.ServiceBackendPort{
+										Number: port,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		if host != "true" {
+			ingress.Spec.Rules[i].Host = host
+			tlsHosts = append(tlsHosts, host)
+		}
+	}
+	ingress.Spec.TLS = ingressTLS(service.ExposeServiceTLS, tlsHosts)
+
+	return ingress
+}
+
+// initIngressV1beta1 initializes the extensions/v1beta1 Ingress of a service,
+// the IngressClass is given by the kubernetes.io/ingress.class annotation as the field doesn't exist before 1.18
+func (k *Kubernetes) initIngressV1beta1(name string, service kobject.ServiceConfig, port int32) *networkingv1beta1.Ingress {
 	hosts := regexp.MustCompile("[ ,]*,[ ,]*").Split(service.ExposeService, -1)
 
+	annotations := ingressAnnotations(service)
+	if service.ExposeServiceIngressClassName != "" {
+		k.Report.Warn(name, "labels", "Ingress class of service %q is set with the deprecated kubernetes.io/ingress.class annotation for the target Kubernetes version", name)
+		annotations["kubernetes.io/ingress.class"] = service.ExposeServiceIngressClassName
+	}
+
 	ingress := &networkingv1beta1.Ingress{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Ingress",
@@ -414,13 +704,13 @@ func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, por
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        name,
 			Labels:      transformer.ConfigLabels(name),
-			Annotations: transformer.ConfigAnnotations(service),
+			Annotations: annotations,
 		},
 		Spec: networkingv1beta1.IngressSpec{
 			Rules: make([]networkingv1beta1.IngressRule, len(hosts)),
 		},
 	}
-	tlsHosts := make([]string, len(hosts))
+	var tlsHosts []string
 	for i, host := range hosts {
 		host, p := transformer.ParseIngressPath(host)
 		ingress.Spec.Rules[i] = networkingv1beta1.IngressRule{
@@ -442,24 +732,11 @@ func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, por
 		}
 		if host != "true" {
 			ingress.Spec.Rules[i].Host = host
-			tlsHosts[i] = host
+			tlsHosts = append(tlsHosts, host)
 		}
 	}
-	if service.ExposeServiceTLS != "" {
-		if service.ExposeServiceTLS != "true" {
-			ingress.Spec.TLS = []networkingv1beta1.IngressTLS{
-				{
-					Hosts:      tlsHosts,
-					SecretName: service.ExposeServiceTLS,
-				},
-			}
-		} else {
-			ingress.Spec.TLS = []networkingv1beta1.IngressTLS{
-				{
-					Hosts: tlsHosts,
-				},
-			}
-		}
+	for _, tls := range ingressTLS(service.ExposeServiceTLS, tlsHosts) {
+		ingress.Spec.TLS = append(ingress.Spec.TLS, networkingv1beta1.IngressTLS{Hosts: tls.Hosts, SecretName: tls.SecretName})
 	}
 
 	return ingress
@@ -469,29 +746,36 @@ func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, por
 func (k *Kubernetes) CreateSecrets(komposeObject kobject.KomposeObject) ([]*api.Secret, error) {
 	var objects []*api.Secret
 	for name, config := range komposeObject.Secrets {
-		if config.File != "" {
+		var data []byte
+		if content, ok := komposeObject.SecretsContent[name]; ok {
+			data = []byte(content)
+		} else if config.External.External {
+			// the secret already exists in the cluster
+			continue
+		} else if config.File != "" {
 			dataString, err := GetContentFromFile(config.File)
 			if err != nil {
-				log.Fatal("unable to read secret from file: ", config.File)
-				return nil, err
+				return nil, errors.Wrapf(err, "unable to read secret from file %s", config.File)
 			}
-			data := []byte(dataString)
-			secret := &api.Secret{
-				TypeMeta: metav1.TypeMeta{
-					Kind:       "Secret",
-					APIVersion: "v1",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   FormatResourceName(name),
-					Labels: transformer.ConfigLabels(name),
-				},
-				Type: api.SecretTypeOpaque,
-				Data: map[string][]byte{name: data},
-			}
-			objects = append(objects, secret)
+			data = []byte(dataString)
 		} else {
-			log.Warnf("External secrets %s is not currently supported - ignoring", name)
+			k.Report.Warn("", "secrets", "Secret %s has neither a file nor an environment variable - ignoring", name)
+			continue
+		}
+
+		secret := &api.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Secret",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   FormatResourceName(name),
+				Labels: transformer.ConfigLabels(name),
+			},
+			Type: api.SecretTypeOpaque,
+			Data: map[string][]byte{name: data},
 		}
+		objects = append(objects, secret)
 	}
 	return objects, nil
 }
@@ -535,6 +819,74 @@ func (k *Kubernetes) CreatePVC(name string, mode string, size string, selectorVa
 	return pvc, nil
 }
 
+// CreateNamedVolumePVCs creates a single PVC for each named volume mounted by several services,
+// instead of one claim per service. A warning is logged when such a volume is ReadWriteOnce
+// while its consumers run in pods that may be scheduled on different nodes.
+func (k *Kubernetes) CreateNamedVolumePVCs(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) ([]*api.PersistentVolumeClaim, error) {
+	var names []string
+	for name := range komposeObject.NamedVolumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pvcs []*api.PersistentVolumeClaim
+	for _, name := range names {
+		volume := komposeObject.NamedVolumes[name]
+		if len(volume.Consumers) < 2 || volume.External || volume.NFSServer != "" {
+			continue
+		}
+
+		size := PVCRequestSize
+		if len(volume.PVCSize) > 0 {
+			size = volume.PVCSize
+		}
+		pvc, err := k.CreatePVC(volume.Name, "", size, volume.SelectorValue)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to create the PVC of volume %s", volume.Name)
+		}
+		if volume.StorageClass != "" {
+			pvc.Spec.StorageClassName = &volume.StorageClass
+		}
+		if volume.VolumeMode != "" {
+			volumeMode := api.PersistentVolumeMode(volume.VolumeMode)
+			pvc.Spec.VolumeMode = &volumeMode
+		}
+		if volume.AccessMode != "" {
+			pvc.Spec.AccessModes = []api.PersistentVolumeAccessMode{api.PersistentVolumeAccessMode(volume.AccessMode)}
+		}
+
+		accessMode := pvc.Spec.AccessModes[0]
+		if (accessMode == api.ReadWriteOnce || accessMode == api.ReadWriteOncePod) && volumeSpansPods(volume, komposeObject, opt) {
+			k.Report.Warn("", "volumes", "Volume %s is %s but mounted by the services %s, whose pods may be scheduled on different nodes and fail to mount it", volume.Name, accessMode, strings.Join(volume.Consumers, ", "))
+		}
+		pvcs = append(pvcs, pvc)
+	}
+	return pvcs, nil
+}
+
+// volumeSpansPods returns true if the consumers of the named volume run in more than one pod.
+// Services of the same group share a pod in multiple container mode.
+func volumeSpansPods(volume kobject.NamedVolume, komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) bool {
+	pods := make(map[string]bool)
+	for _, name := range volume.Consumers {
+		service := komposeObject.ServiceConfigs[name]
+		pod := name
+		if group, ok := service.Labels[compose.LabelServiceGroup]; ok && opt.MultipleContainerMode {
+			pod = group
+		}
+		pods[pod] = true
+
+		replicas := service.Replicas
+		if opt.IsReplicaSetFlag || replicas == 0 {
+			replicas = opt.Replicas
+		}
+		if replicas > 1 {
+			return true
+		}
+	}
+	return len(pods) > 1
+}
+
 // ConfigPorts configures the container ports.
 func ConfigPorts(name string, service kobject.ServiceConfig) []api.ContainerPort {
 	ports := []api.ContainerPort{}
@@ -614,7 +966,7 @@ func (k *Kubernetes) ConfigServicePorts(name string, service kobject.ServiceConf
 		if _, ok := seenPorts[int(port.HostPort)]; ok {
 			// https://github.com/kubernetes/kubernetes/issues/2995
 			if service.ServiceType == string(api.ServiceTypeLoadBalancer) {
-				log.Fatalf("Service %s of type LoadBalancer cannot use TCP and UDP for the same port", name)
+				k.Report.Warn(name, "ports", "Service %s of type LoadBalancer cannot use TCP and UDP for the same port", name)
 			}
 			name = fmt.Sprintf("%s-%s", name, strings.ToLower(string(port.Protocol)))
 		}
@@ -686,6 +1038,46 @@ func (k *Kubernetes) ConfigTmpfs(name string, service kobject.ServiceConfig) ([]
 	return volumeMounts, volumes
 }
 
+// ConfigShmSize configures /dev/shm as a memory-backed emptyDir volume limited to the shm_size of the service
+func (k *Kubernetes) ConfigShmSize(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume) {
+	volumeName := fmt.Sprintf("%s-shm", name)
+	volSource := k.ConfigEmptyVolumeSource("tmpfs")
+	volSource.EmptyDir.SizeLimit = resource.NewQuantity(service.ShmSize, resource.BinarySI)
+
+	volumeMounts := []api.VolumeMount{{Name: volumeName, MountPath: "/dev/shm"}}
+	volumes := []api.Volume{{Name: volumeName, VolumeSource: *volSource}}
+	return volumeMounts, volumes
+}
+
+// ConfigDevices mounts the host devices of a service as hostPath volumes,
+// unless a device plugin resource is requested instead with the kompose.devices.resource label
+func (k *Kubernetes) ConfigDevices(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume) {
+	if service.DevicesResource != "" {
+		return nil, nil
+	}
+
+	var volumeMounts []api.VolumeMount
+	var volumes []api.Volume
+	for index, device := range service.Devices {
+		parts := strings.Split(device, ":")
+		hostPath, containerPath := parts[0], parts[0]
+		if len(parts) > 1 && parts[1] != "" {
+			containerPath = parts[1]
+		}
+		volumeName := fmt.Sprintf("%s-device%d", name, index)
+		volumeMounts = append(volumeMounts, api.VolumeMount{Name: volumeName, MountPath: containerPath})
+		volumes = append(volumes, api.Volume{
+			Name:         volumeName,
+			VolumeSource: api.VolumeSource{HostPath: &api.HostPathVolumeSource{Path: hostPath}},
+		})
+	}
+	if len(volumes) > 0 {
+		k.Report.Warn(name, "devices", "Service %q mounts host devices as hostPath volumes, its container needs to be privileged to use them, "+
+			"a device plugin resource can be requested instead with the %s label", name, compose.LabelDevicesResource)
+	}
+	return volumeMounts, volumes
+}
+
 // ConfigSecretVolumes config volumes from secret.
 // Link: https://docs.docker.com/compose/compose-file/#secrets
 // In kubernetes' Secret resource, it has a data structure like a map[string]bytes, every key will act like the file name
@@ -695,77 +1087,82 @@ func (k *Kubernetes) ConfigTmpfs(name string, service kobject.ServiceConfig) ([]
 func (k *Kubernetes) ConfigSecretVolumes(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume) {
 	var volumeMounts []api.VolumeMount
 	var volumes []api.Volume
-	if len(service.Secrets) > 0 {
-		for _, secretConfig := range service.Secrets {
-			if secretConfig.UID != "" {
-				log.Warnf("Ignore pid in secrets for service: %s", name)
+	for _, secretConfig := range service.Secrets {
+		secretName, key := secretReference(secretConfig.Source, service)
+
+		var mountPath, subPath string
+		var items []api.KeyToPath
+		// if is used the short-syntax
+		if secretConfig.Target == "" {
+			// the secret is inside the directory /run/secrets/<source>
+			mountPath = "/run/secrets/" + secretConfig.Source
+			if key != "" {
+				items = []api.KeyToPath{{Key: key, Path: secretConfig.Source}}
 			}
-			if secretConfig.GID != "" {
-				log.Warnf("Ignore gid in secrets for service: %s", name)
+		} else {
+			// the long-syntax target is the path of the file, relative to /run/secrets if it isn't absolute
+			mountPath = secretConfig.Target
+			if !strings.HasPrefix(mountPath, "/") {
+				mountPath = "/run/secrets/" + mountPath
 			}
-
-			var itemPath string // should be the filename
-			var mountPath = ""  // should be the directory
-			// if is used the short-syntax
-			if secretConfig.Target == "" {
-				// the secret path (mountPath) should be inside the default directory /run/secrets
-				mountPath = "/run/secrets/" + secretConfig.Source
-				// the itemPath should be the source itself
-				itemPath = secretConfig.Source
-			} else {
-				// if is the long-syntax, i should get the last part of path and consider it the filename
-				pathSplitted := strings.Split(secretConfig.Target, "/")
-				lastPart := pathSplitted[len(pathSplitted)-1]
-
-				// if the filename (lastPart) and the target is the same
-				if lastPart == secretConfig.Target {
-					// the secret path should be the source (it need to be inside a directory and only the filename was given)
-					mountPath = secretConfig.Source
-				} else {
-					// should then get the target without the filename (lastPart)
-					mountPath = mountPath + strings.TrimSuffix(secretConfig.Target, "/"+lastPart) // menos ultima parte
-				}
-
-				// if the target isn't absolute path
-				if strings.HasPrefix(secretConfig.Target, "/") == false {
-					// concat the default secret directory
-					mountPath = "/run/secrets/" + mountPath
-				}
-
-				itemPath = lastPart
+			// mount only that file, the other files of the target directory stay visible
+			if key != "" {
+				subPath = path.Base(mountPath)
+				items = []api.KeyToPath{{Key: key, Path: subPath}}
 			}
+		}
 
-			volSource := api.VolumeSource{
-				Secret: &api.SecretVolumeSource{
-					SecretName: secretConfig.Source,
-					Items: []api.KeyToPath{{
-						Key:  secretConfig.Source,
-						Path: itemPath,
-					}},
-				},
-			}
+		volSource := api.VolumeSource{
+			Secret: &api.SecretVolumeSource{
+				SecretName: secretName,
+				Items:      items,
+			},
+		}
 
-			if secretConfig.Mode != nil {
-				mode := cast.ToInt32(*secretConfig.Mode)
+		if secretConfig.Mode != nil {
+			mode := cast.ToInt32(*secretConfig.Mode)
+			if len(items) > 0 {
+				volSource.Secret.Items[0].Mode = &mode
+			} else {
 				volSource.Secret.DefaultMode = &mode
 			}
+		}
 
-			vol := api.Volume{
-				Name:         secretConfig.Source,
-				VolumeSource: volSource,
-			}
-			volumes = append(volumes, vol)
+		vol := api.Volume{
+			Name:         secretConfig.Source,
+			VolumeSource: volSource,
+		}
+		volumes = append(volumes, vol)
 
-			volMount := api.VolumeMount{
-				Name:      vol.Name,
-				MountPath: mountPath,
-			}
-			volumeMounts = append(volumeMounts, volMount)
+		volMount := api.VolumeMount{
+			Name:      vol.Name,
+			MountPath: mountPath,
+			SubPath:   subPath,
 		}
+		volumeMounts = append(volumeMounts, volMount)
 	}
 	return volumeMounts, volumes
 }
 
+// secretReference returns the name of the Secret and its key mounted for the given secret of the service.
+// External secrets refer to an existing Secret, named by the kompose.secret.name label or the secret name,
+// the whole Secret is mounted unless the kompose.secret.key label gives the key.
+func secretReference(source string, service kobject.ServiceConfig) (string, string) {
+	secret, ok := service.SecretsMetaData[source]
+	if !ok || !secret.External.External {
+		return FormatResourceName(source), source
+	}
+
+	name := secret.Name
+	if label, ok := secret.Labels[compose.LabelSecretName]; ok {
+		name = label
+	}
+	if name == "" {
+		name = source
+	}
+	return name, secret.Labels[compose.LabelSecretKey]
+}
+
 // ConfigVolumes configure the container volumes.
 func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume, []*api.PersistentVolumeClaim, []*api.ConfigMap, error) {
 	volumeMounts := []api.VolumeMount{}
@@ -840,9 +1237,21 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 					volMount.SubPath = volsource.ConfigMap.Items[0].Path
 				}
 			}
+		} else if volume.External {
+			// the claim already exists in the cluster
+			volsource = k.ConfigPVCVolumeSource(volume.ClaimName, readonly)
+		} else if volume.NFSServer != "" {
+			volsource = &api.VolumeSource{
+				NFS: &api.NFSVolumeSource{
+					Server:   volume.NFSServer,
+					Path:     volume.NFSPath,
+					ReadOnly: readonly,
+				},
+			}
 		} else {
 			volsource = k.ConfigPVCVolumeSource(volumeName, readonly)
-			if volume.VFrom == "" {
+			// the claim of a shared named volume is created once by CreateNamedVolumePVCs
+			if volume.VFrom == "" && !volume.Shared {
 				defaultSize := PVCRequestSize
 
 				if len(volume.PVCSize) > 0 {
@@ -860,6 +1269,17 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 				if err != nil {
 					return nil, nil, nil, nil, errors.Wrap(err, "k.CreatePVC failed")
 				}
+				if volume.StorageClass != "" {
+					storageClass := volume.StorageClass
+					createdPVC.Spec.StorageClassName = &storageClass
+				}
+				if volume.VolumeMode != "" {
+					volumeMode := api.PersistentVolumeMode(volume.VolumeMode)
+					createdPVC.Spec.VolumeMode = &volumeMode
+				}
+				if volume.AccessMode != "" {
+					createdPVC.Spec.AccessModes = []api.PersistentVolumeAccessMode{api.PersistentVolumeAccessMode(volume.AccessMode)}
+				}
 
 				PVCs = append(PVCs, createdPVC)
 			}
@@ -874,7 +1294,7 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 		volumes = append(volumes, vol)
 
 		if len(volume.Host) > 0 && (!useHostPath && !useConfigMap) {
-			log.Warningf("Volume mount on the host %q isn't supported - ignoring path on the host", volume.Host)
+			k.Report.Warn(name, "volumes", "Volume mount on the host %q isn't supported - ignoring path on the host", volume.Host)
 		}
 	}
 
@@ -1002,7 +1422,7 @@ func ConfigEnvs(name string, service kobject.ServiceConfig, opt kobject.ConvertO
 }
 
 // CreateKubernetesObjects generates a Kubernetes artifact for each input type service
-func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.ServiceConfig, opt kobject.ConvertOptions) []runtime.Object {
+func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.ServiceConfig, komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) ([]runtime.Object, error) {
 	var objects []runtime.Object
 	var replica int
 
@@ -1019,7 +1439,7 @@ func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.Servic
 			opt.CreateD = false
 			opt.CreateDS = true
 		} else if opt.Controller != "daemonset" {
-			log.Warnf("Global deploy mode service is best converted to daemonset, now it convert to %s", opt.Controller)
+			k.Report.Warn(name, "deploy.mode", "Global deploy mode service is best converted to daemonset, now it convert to %s", opt.Controller)
 		}
 	}
 
@@ -1029,13 +1449,25 @@ func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.Servic
 		opt.CreateDS = false
 		opt.CreateRC = false
 		if opt.Controller != "" {
-			log.Warnf("Use label %s type %s for service %s, ignore %s flags", compose.LabelControllerType, val, name, opt.Controller)
+			k.Report.Warn(name, "labels", "Use label %s type %s for service %s, ignore %s flags", compose.LabelControllerType, val, name, opt.Controller)
 		}
-		opt.Controller = val
+		opt.Controller = strings.ToLower(val)
+	}
+
+	// a schedule always makes a CronJob
+	if isJob(service, opt) {
+		opt.CreateD = false
+		opt.CreateDS = false
+		opt.CreateRC = false
+		opt.Controller = JobController
 	}
 
 	if len(service.Configs) > 0 {
-		objects = k.createConfigMapFromComposeConfig(name, opt, service, objects)
+		var err error
+		objects, err = k.createConfigMapFromComposeConfig(name, opt, service, komposeObject.ConfigsContent, objects)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if opt.CreateD || opt.Controller == DeploymentController {
@@ -1046,28 +1478,105 @@ func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.Servic
 		objects = append(objects, k.InitDS(name, service))
 	}
 
+	if opt.CreateRC || opt.Controller == ReplicationController {
+		objects = append(objects, k.InitRC(name, service, replica))
+	}
+
+	if opt.Controller == StatefulSetController {
+		objects = append(objects, k.InitSS(name, service, replica))
+	}
+
+	if opt.Controller == JobController {
+		if service.CronJobSchedule != "" {
+			objects = append(objects, k.InitCronJob(name, service))
+		} else {
+			objects = append(objects, k.InitJob(name, service))
+		}
+	}
+
 	if len(service.EnvFile) > 0 {
 		for _, envFile := range service.EnvFile {
-			configMap := k.InitConfigMapForEnv(name, service, opt, envFile)
+			configMap, err := k.InitConfigMapForEnv(name, service, opt, envFile)
+			if err != nil {
+				return nil, err
+			}
 			objects = append(objects, configMap)
 		}
 	}
 
-	return objects
+	return objects, nil
+}
+
+// isStatefulSet checks if the service is converted to a StatefulSet,
+// the kompose.controller.type label takes precedence over the --controller flag
+func isStatefulSet(service kobject.ServiceConfig, opt kobject.ConvertOptions) bool {
+	if val, ok := service.Labels[compose.LabelControllerType]; ok {
+		return strings.ToLower(val) == StatefulSetController
+	}
+	return opt.Controller == StatefulSetController
+}
+
+// isJob checks if the service is converted to a Job or a CronJob,
+// the kompose.cronjob.schedule label implies a CronJob whatever the controller is
+func isJob(service kobject.ServiceConfig, opt kobject.ConvertOptions) bool {
+	if service.CronJobSchedule != "" {
+		return true
+	}
+	if val, ok := service.Labels[compose.LabelControllerType]; ok {
+		return strings.ToLower(val) == JobController
+	}
+	return opt.Controller == JobController
+}
+
+// jobRestartPolicy returns the restart option used for the pods of a Job,
+// Jobs only support the Never and OnFailure restart policies
+func jobRestartPolicy(name string, service kobject.ServiceConfig, report *kobject.Report) string {
+	switch service.Restart {
+	case "no", "on-failure":
+		return service.Restart
+	case "":
+		return "no"
+	default:
+		report.Warn(name, "restart", "Restart policy '%s' in service %s is not supported by Job, convert it to 'on-failure'", service.Restart, name)
+		return "on-failure"
+	}
+}
+
+// governingServiceType returns the service type used for the governing service of a StatefulSet.
+// The governing service must be headless to give every replica a stable network identity,
+// so a ClusterIP service is turned into a headless one.
+func governingServiceType(name string, service kobject.ServiceConfig, report *kobject.Report) string {
+	switch service.ServiceType {
+	case "", string(api.ServiceTypeClusterIP), compose.ServiceTypeHeadless:
+		return compose.ServiceTypeHeadless
+	default:
+		report.Warn(name, "labels", "Service %q of type %s is used as governing service of the StatefulSet, pods won't get a stable network identity", name, service.ServiceType)
+		return service.ServiceType
+	}
 }
 
-func (k *Kubernetes) createConfigMapFromComposeConfig(name string, opt kobject.ConvertOptions, service kobject.ServiceConfig, objects []runtime.Object) []runtime.Object {
+// createConfigMapFromComposeConfig creates the ConfigMaps of the configs of a service,
+// from the content of the configs defined inline or from their file
+func (k *Kubernetes) createConfigMapFromComposeConfig(name string, opt kobject.ConvertOptions, service kobject.ServiceConfig, configsContent map[string]string, objects []runtime.Object) ([]runtime.Object, error) {
 	for _, config := range service.Configs {
 		currentConfigName := config.Source
+		if content, ok := configsContent[currentConfigName]; ok {
+			objects = append(objects, k.InitConfigMapFromContent(name, currentConfigName, content))
+			continue
+		}
 		currentConfigObj := service.ConfigsMetaData[currentConfigName]
+		// external configs refer to an existing ConfigMap, see configMapReference
 		if currentConfigObj.External.External {
 			continue
 		}
 		currentFileName := currentConfigObj.File
-		configMap := k.InitConfigMapFromFile(name, service, currentFileName)
+		configMap, err := k.InitConfigMapFromFile(name, service, currentFileName)
+		if err != nil {
+			return nil, err
+		}
 		objects = append(objects, configMap)
 	}
-	return objects
+	return objects, nil
 }
 
 // InitPod initializes Kubernetes Pod object
@@ -1087,6 +1596,145 @@ func (k *Kubernetes) InitPod(name string, service kobject.ServiceConfig) *api.Po
 	return &pod
 }
 
+// CreateHPA creates the HorizontalPodAutoscaler of a service from the kompose.hpa.* labels, nil without the labels.
+// It scales the Deployment or the StatefulSet of the service, whose containers must request the resources
+// the utilization is computed from. The CPU utilization targets 80% if neither the CPU nor the memory is given.
+func (k *Kubernetes) CreateHPA(name string, service kobject.ServiceConfig, objects []runtime.Object) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if service.HPAMaxReplicas == 0 {
+		return nil, nil
+	}
+
+	var target autoscalingv2.CrossVersionObjectReference
+	var containers []api.Container
+	for _, obj := range objects {
+		switch t := obj.(type) {
+		case *appsv1.Deployment:
+			target = autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: t.Name, APIVersion: "apps/v1"}
+			containers = t.Spec.Template.Spec.Containers
+		case *appsv1.StatefulSet:
+			target = autoscalingv2.CrossVersionObjectReference{Kind: "StatefulSet", Name: t.Name, APIVersion: "apps/v1"}
+			containers = t.Spec.Template.Spec.Containers
+		}
+	}
+	if target.Kind == "" {
+		return nil, errors.Errorf("kompose.hpa.* labels of service %q require a Deployment or a StatefulSet", name)
+	}
+
+	cpu, memory := service.HPACPU, service.HPAMemory
+	if cpu == 0 && memory == 0 {
+		cpu = 80
+	}
+
+	var metrics []autoscalingv2.MetricSpec
+	for _, m := range []struct {
+		resource    api.ResourceName
+		utilization int32
+		reservation string
+	}{
+		{api.ResourceCPU, cpu, "deploy.resources.reservations.cpus"},
+		{api.ResourceMemory, memory, "deploy.resources.reservations.memory"},
+	} {
+		if m.utilization == 0 {
+			continue
+		}
+		for _, c := range containers {
+			if _, ok := c.Resources.Requests[m.resource]; !ok {
+				return nil, errors.Errorf("service %q targets a %s utilization of %d%% but container %q has no %s request, set %s", name, m.resource, m.utilization, c.Name, m.resource, m.reservation)
+			}
+		}
+		utilization := m.utilization
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: m.resource,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &utilization,
+				},
+			},
+		})
+	}
+
+	minReplicas := service.HPAMinReplicas
+	if minReplicas == 0 {
+		minReplicas = 1
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: "autoscaling/v2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigLabels(name),
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: target,
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    service.HPAMaxReplicas,
+			Metrics:        metrics,
+		},
+	}
+	return hpa, nil
+}
+
+// CreatePDB creates the PodDisruptionBudget of a service run by a Deployment or a StatefulSet with more than one replica,
+// nil if the service has none. The PodDisruptionBudget is created with the --pdb option or the kompose.pdb.min-available label,
+// which sets its minAvailable. Otherwise maxUnavailable is deploy.update_config.parallelism, 1 by default.
+func (k *Kubernetes) CreatePDB(name string, service kobject.ServiceConfig, objects []runtime.Object, opt kobject.ConvertOptions) *policyv1.PodDisruptionBudget {
+	if !opt.CreatePDB && service.PDBMinAvailable == "" {
+		return nil
+	}
+
+	var replicas *int32
+	var labels map[string]string
+	for _, obj := range objects {
+		switch t := obj.(type) {
+		case *appsv1.Deployment:
+			replicas, labels = t.Spec.Replicas, t.Spec.Template.Labels
+		case *appsv1.StatefulSet:
+			replicas, labels = t.Spec.Replicas, t.Spec.Template.Labels
+		}
+	}
+	if replicas == nil || *replicas < 2 {
+		if service.PDBMinAvailable != "" {
+			k.Report.Warn(name, "labels", "PodDisruptionBudget of service %q won't be created because it doesn't run more than one replica of a Deployment or a StatefulSet", name)
+		}
+		return nil
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: "policy/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: transformer.ConfigLabels(name),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+		},
+	}
+
+	if service.PDBMinAvailable != "" {
+		minAvailable := intstr.Parse(service.PDBMinAvailable)
+		pdb.Spec.MinAvailable = &minAvailable
+		return pdb
+	}
+
+	maxUnavailable := intstr.FromInt(1)
+	// a parallelism of 0 updates all the replicas at once, which isn't a disruption budget
+	if parallelism := service.DeployUpdateConfig.Parallelism; parallelism != nil && *parallelism > 0 {
+		maxUnavailable = intstr.FromInt(cast.ToInt(*parallelism))
+	}
+	pdb.Spec.MaxUnavailable = &maxUnavailable
+	return pdb
+}
+
 // CreateNetworkPolicy initializes Network policy
 func (k *Kubernetes) CreateNetworkPolicy(name string, networkName string) (*networkingv1.NetworkPolicy, error) {
 	str := "true"
@@ -1119,6 +1767,8 @@ func (k *Kubernetes) CreateNetworkPolicy(name string, networkName string) (*netw
 // Transform maps komposeObject to k8s objects
 // returns object that are already sorted in the way that Services are first
 func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) ([]runtime.Object, error) {
+	k.Report = komposeObject.Report
+
 	// this will hold all the converted data
 	var allobjects []runtime.Object
 
@@ -1132,6 +1782,18 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 		}
 	}
 
+	pvcs, err := k.CreateNamedVolumePVCs(komposeObject, opt)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		allobjects = append(allobjects, pvc)
+	}
+
+	if opt.WaitForDependencies {
+		ConfigHealthyDependencies(&komposeObject)
+	}
+
 	if opt.MultipleContainerMode {
 		komposeObjectToServiceConfigGroupMapping := KomposeObjectToServiceConfigGroupMapping(komposeObject)
 		for name, group := range komposeObjectToServiceConfigGroupMapping {
@@ -1166,7 +1828,7 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 				// Push the built image to the repo!
 				if opt.PushImage {
 					log.Infof("Push image enabled. Attempting to push image '%s'", service.Image)
-					err = transformer.PushDockerImage(service, name)
+					err = transformer.PushDockerImage(service, name, k.Report)
 					if err != nil {
 						return nil, errors.Wrapf(err, "Unable to push Docker image for service %v", name)
 					}
@@ -1179,13 +1841,25 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 			for _, service := range group {
 				podSpec.Append(AddContainer(service, opt))
 
+				if isJob(service, opt) {
+					service.Restart = jobRestartPolicy(name, service, k.Report)
+				}
+
 				// Generate pod only and nothing more
-				if (service.Restart == "no" || service.Restart == "on-failure") && !opt.IsPodController() {
+				if (service.Restart == "no" || service.Restart == "on-failure") && !opt.IsPodController() && !isJob(service, opt) {
 					log.Infof("Create kubernetes pod instead of pod controller due to restart policy: %s", service.Restart)
 					pod := k.InitPod(name, service)
 					objects = append(objects, pod)
 				} else {
-					objects = k.CreateKubernetesObjects(name, service, opt)
+					var err error
+					objects, err = k.CreateKubernetesObjects(name, service, komposeObject, opt)
+					if err != nil {
+						return nil, errors.Wrapf(err, "Unable to create Kubernetes objects for service %v", name)
+					}
+				}
+
+				if isStatefulSet(service, opt) {
+					service.ServiceType = governingServiceType(name, service, k.Report)
 				}
 
 				if k.PortsExist(service) {
@@ -1195,13 +1869,13 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 							objects = append(objects, svc)
 						}
 						if len(svcs) > 1 {
-							log.Warningf("Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
+							k.Report.Warn(name, "ports", "Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
 						}
 					} else {
 						svc := k.CreateService(name, service, objects)
 						objects = append(objects, svc)
 						if service.ExposeService != "" {
-							objects = append(objects, k.initIngress(name, service, svc.Spec.Ports[0].Port))
+							objects = append(objects, k.initIngress(name, service, svc.Spec.Ports[0].Port, opt))
 						}
 					}
 				} else {
@@ -1209,7 +1883,7 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 						svc := k.CreateHeadlessService(name, service, objects)
 						objects = append(objects, svc)
 					} else {
-						log.Warnf("Service %q won't be created because 'ports' is not specified", name)
+						k.Report.Warn(name, "ports", "Service %q won't be created because 'ports' is not specified", name)
 					}
 				}
 
@@ -1218,6 +1892,20 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 				if err != nil {
 					return nil, errors.Wrap(err, "k.ConfigVolumes failed")
 				}
+				// Configure shm_size
+				if service.ShmSize > 0 {
+					shmVolumesMount, shmVolumes := k.ConfigShmSize(name, service)
+					volumes = append(volumes, shmVolumes...)
+					volumesMount = append(volumesMount, shmVolumesMount...)
+				}
+
+				// Configure devices
+				devicesMount, devices := k.ConfigDevices(name, service)
+				volumes = append(volumes, devices...)
+				volumesMount = append(volumesMount, devicesMount...)
+
+				// StatefulSet claims its storage through volumeClaimTemplates
+				volumes, pvc = ConfigStatefulSetClaims(objects, volumes, pvc)
 				podSpec.Append(
 					SetVolumeMounts(volumesMount),
 					SetVolumes(volumes),
@@ -1251,20 +1939,43 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 					SetPorts(name, service),
 					ImagePullPolicy(name, service),
 					RestartPolicy(name, service),
-					SecurityContext(name, service),
+					SecurityContext(name, service, k.Report),
 					LivenessProbe(service),
 					ReadinessProbe(service),
 					HostName(service),
 					DomainName(service),
+					HostAliases(name, service, k.Report),
+					DNSConfig(name, service, k.Report),
 					ResourcesLimits(service),
 					ResourcesRequests(service),
-					TerminationGracePeriodSeconds(name, service),
+					DeviceResources(service, opt),
+					TerminationGracePeriodSeconds(name, service, k.Report),
 				)
 
+				if opt.WaitForDependencies {
+					podSpec.Append(InitContainers(ConfigInitContainers(name, service, komposeObject, opt)))
+				}
+
+				if err := podSpec.Err(); err != nil {
+					return nil, errors.Wrapf(err, "Unable to configure the pod of service %v", name)
+				}
+
 				err = k.UpdateKubernetesObjectsMultipleContainers(name, service, opt, &objects, podSpec)
 				if err != nil {
 					return nil, errors.Wrap(err, "Error transforming Kubernetes objects")
 				}
+
+				hpa, err := k.CreateHPA(name, service, objects)
+				if err != nil {
+					return nil, err
+				}
+				if hpa != nil {
+					objects = append(objects, hpa)
+				}
+
+				if pdb := k.CreatePDB(name, service, objects, opt); pdb != nil {
+					objects = append(objects, pdb)
+				}
 			}
 
 			if len(service.Network) > 0 {
@@ -1315,20 +2026,32 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 				// Push the built image to the repo!
 				if opt.PushImage {
 					log.Infof("Push image enabled. Attempting to push image '%s'", service.Image)
-					err = transformer.PushDockerImage(service, name)
+					err = transformer.PushDockerImage(service, name, k.Report)
 					if err != nil {
 						return nil, errors.Wrapf(err, "Unable to push Docker image for service %v", name)
 					}
 				}
 			}
 
+			if isJob(service, opt) {
+				service.Restart = jobRestartPolicy(name, service, k.Report)
+			}
+
 			// Generate pod only and nothing more
-			if (service.Restart == "no" || service.Restart == "on-failure") && !opt.IsPodController() {
+			if (service.Restart == "no" || service.Restart == "on-failure") && !opt.IsPodController() && !isJob(service, opt) {
 				log.Infof("Create kubernetes pod instead of pod controller due to restart policy: %s", service.Restart)
 				pod := k.InitPod(name, service)
 				objects = append(objects, pod)
 			} else {
-				objects = k.CreateKubernetesObjects(name, service, opt)
+				var err error
+				objects, err = k.CreateKubernetesObjects(name, service, komposeObject, opt)
+				if err != nil {
+					return nil, errors.Wrapf(err, "Unable to create Kubernetes objects for service %v", name)
+				}
+			}
+
+			if isStatefulSet(service, opt) {
+				service.ServiceType = governingServiceType(name, service, k.Report)
 			}
 
 			if k.PortsExist(service) {
@@ -1338,13 +2061,13 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 						objects = append(objects, svc)
 					}
 					if len(svcs) > 1 {
-						log.Warningf("Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
+						k.Report.Warn(name, "ports", "Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
 					}
 				} else {
 					svc := k.CreateService(name, service, objects)
 					objects = append(objects, svc)
 					if service.ExposeService != "" {
-						objects = append(objects, k.initIngress(name, service, svc.Spec.Ports[0].Port))
+						objects = append(objects, k.initIngress(name, service, svc.Spec.Ports[0].Port, opt))
 					}
 				}
 			} else {
@@ -1352,7 +2075,7 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 					svc := k.CreateHeadlessService(name, service, objects)
 					objects = append(objects, svc)
 				} else {
-					log.Warnf("Service %q won't be created because 'ports' is not specified", name)
+					k.Report.Warn(name, "ports", "Service %q won't be created because 'ports' is not specified", name)
 				}
 			}
 
@@ -1361,6 +2084,25 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 				return nil, errors.Wrap(err, "Error transforming Kubernetes objects")
 			}
 
+			hpa, err := k.CreateHPA(name, service, objects)
+			if err != nil {
+				return nil, err
+			}
+			if hpa != nil {
+				objects = append(objects, hpa)
+			}
+
+			if pdb := k.CreatePDB(name, service, objects, opt); pdb != nil {
+				objects = append(objects, pdb)
+			}
+
+			if opt.WaitForDependencies {
+				err = k.AddInitContainers(objects, ConfigInitContainers(name, service, komposeObject, opt))
+				if err != nil {
+					return nil, errors.Wrap(err, "Error adding init containers")
+				}
+			}
+
 			if len(service.Network) > 0 {
 				for _, net := range service.Network {
 					log.Infof("Network %s is detected at Source, shall be converted to equivalent NetworkPolicy at Destination", net)
@@ -1381,6 +2123,22 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 	k.SortServicesFirst(&allobjects)
 	k.RemoveDupObjects(&allobjects)
 	// k.FixWorkloadVersion(&allobjects)
+	if err := SetAPIVersions(allobjects, opt.KubeVersion); err != nil {
+		return nil, err
+	}
+	if err := SetAppArmorProfiles(allobjects, opt.KubeVersion); err != nil {
+		return nil, err
+	}
+
+	namespace := GetNamespace(komposeObject, opt)
+	SetNamespace(allobjects, namespace)
+	if opt.CreateNamespace {
+		if namespace == "" {
+			k.Report.Warn("", "", "No namespace is given, the Namespace object won't be created")
+		} else {
+			allobjects = append([]runtime.Object{k.InitNamespace(namespace)}, allobjects...)
+		}
+	}
 
 	return allobjects, nil
 }
@@ -1400,6 +2158,30 @@ func (k *Kubernetes) UpdateController(obj runtime.Object, updateTemplate func(*a
 			return errors.Wrap(err, "updateTemplate failed")
 		}
 		updateMeta(&t.ObjectMeta)
+	case *appsv1.StatefulSet:
+		err = updateTemplate(&t.Spec.Template)
+		if err != nil {
+			return errors.Wrap(err, "updateTemplate failed")
+		}
+		updateMeta(&t.ObjectMeta)
+	case *api.ReplicationController:
+		err = updateTemplate(t.Spec.Template)
+		if err != nil {
+			return errors.Wrap(err, "updateTemplate failed")
+		}
+		updateMeta(&t.ObjectMeta)
+	case *batchv1.Job:
+		err = updateTemplate(&t.Spec.Template)
+		if err != nil {
+			return errors.Wrap(err, "updateTemplate failed")
+		}
+		updateMeta(&t.ObjectMeta)
+	case *batchv1.CronJob:
+		err = updateTemplate(&t.Spec.JobTemplate.Spec.Template)
+		if err != nil {
+			return errors.Wrap(err, "updateTemplate failed")
+		}
+		updateMeta(&t.ObjectMeta)
 	case *deployapi.DeploymentConfig:
 		err = updateTemplate(t.Spec.Template)
 		if err != nil {
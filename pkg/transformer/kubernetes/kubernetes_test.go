@@ -23,14 +23,20 @@ import (
 	"strings"
 	"testing"
 
+	dockerCliTypes "github.com/docker/cli/cli/compose/types"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/kubernetes/kompose/pkg/loader/compose"
 	"github.com/kubernetes/kompose/pkg/transformer"
 	deployapi "github.com/openshift/api/apps/v1"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	api "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -224,6 +230,8 @@ func TestKomposeConvertIngress(t *testing.T) {
 	}{
 		"Convert to Ingress: label set to true":        {newKomposeObject(), kobject.ConvertOptions{CreateD: true}, "true"},
 		"Convert to Ingress: label set to example.com": {newKomposeObject(), kobject.ConvertOptions{CreateD: true}, "example.com"},
+		"Convert to Ingress with a path":               {newKomposeObject(), kobject.ConvertOptions{CreateD: true}, "example.com/api"},
+		"Convert to Ingress with a regular expression": {newKomposeObject(), kobject.ConvertOptions{CreateD: true}, "example.com/api(/|$)(.*)"},
 	}
 
 	for name, test := range testCases {
@@ -239,11 +247,18 @@ func TestKomposeConvertIngress(t *testing.T) {
 		config.ExposeService = test.labelValue
 		test.komposeObject.ServiceConfigs[appName] = config
 
+		expectedPath, expectedPathType := "/", networkingv1.PathTypePrefix
 		switch test.labelValue {
 		case "true":
 			expectedHost = ""
 		default:
-			expectedHost = test.labelValue
+			expectedHost, _ = transformer.ParseIngressPath(test.labelValue)
+			if _, p := transformer.ParseIngressPath(test.labelValue); p != "" {
+				expectedPath = p
+			}
+			if strings.Contains(expectedPath, "(") {
+				expectedPathType = networkingv1.PathTypeImplementationSpecific
+			}
 		}
 
 		// Run Transform
@@ -253,22 +268,92 @@ func TestKomposeConvertIngress(t *testing.T) {
 		}
 
 		// Check results
+		found := false
 		for _, obj := range objs {
-			if ing, ok := obj.(*networkingv1beta1.Ingress); ok {
+			if ing, ok := obj.(*networkingv1.Ingress); ok {
+				found = true
 				if ing.ObjectMeta.Name != appName {
 					t.Errorf("Expected ObjectMeta.Name to be %s, got %s instead", appName, ing.ObjectMeta.Name)
 				}
-				if ing.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServiceName != appName {
-					t.Errorf("Expected Backend.ServiceName to be %s, got %s instead", appName, ing.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServiceName)
+				path := ing.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0]
+				if path.Backend.Service.Name != appName {
+					t.Errorf("Expected Backend.Service.Name to be %s, got %s instead", appName, path.Backend.Service.Name)
 				}
-				if ing.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServicePort.IntVal != config.Port[0].HostPort {
-					t.Errorf("Expected Backend.ServicePort to be %d, got %v instead", config.Port[0].HostPort, ing.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServicePort.IntVal)
+				if path.Backend.Service.Port.Number != config.Port[0].HostPort {
+					t.Errorf("Expected Backend.Service.Port.Number to be %d, got %v instead", config.Port[0].HostPort, path.Backend.Service.Port.Number)
+				}
+				if path.Path != expectedPath || *path.PathType != expectedPathType {
+					t.Errorf("Expected path %s of type %s, got %s of type %s instead", expectedPath, expectedPathType, path.Path, *path.PathType)
 				}
 				if ing.Spec.Rules[0].Host != expectedHost {
 					t.Errorf("Expected Rules[0].Host to be %s, got %s instead", expectedHost, ing.Spec.Rules[0].Host)
 				}
 			}
 		}
+		if !found {
+			t.Errorf("Expected a networking.k8s.io/v1 Ingress")
+		}
+	}
+}
+
+func TestKomposeConvertIngressOptions(t *testing.T) {
+	komposeObject := newKomposeObject()
+	config := komposeObject.ServiceConfigs["app"]
+	config.ExposeService = "a.example.com,b.example.com/api"
+	config.ExposeServiceTLS = "a.example.com=a-tls, b.example.com=b-tls"
+	config.ExposeServiceIngressClassName = "nginx"
+	config.ExposeServiceAnnotations = map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"}
+	komposeObject.ServiceConfigs["app"] = config
+
+	k := Kubernetes{}
+	objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.Transform failed"))
+	}
+	for _, obj := range objs {
+		ing, ok := obj.(*networkingv1.Ingress)
+		if !ok {
+			continue
+		}
+		if ing.Spec.IngressClassName == nil || *ing.Spec.IngressClassName != "nginx" {
+			t.Errorf("Expected IngressClassName nginx, got %v", ing.Spec.IngressClassName)
+		}
+		expectedTLS := []networkingv1.IngressTLS{
+			{Hosts: []string{"a.example.com"}, SecretName: "a-tls"},
+			{Hosts: []string{"b.example.com"}, SecretName: "b-tls"},
+		}
+		if !reflect.DeepEqual(ing.Spec.TLS, expectedTLS) {
+			t.Errorf("Expected TLS %#v, got %#v", expectedTLS, ing.Spec.TLS)
+		}
+		if ing.Annotations["nginx.ingress.kubernetes.io/ssl-redirect"] != "true" {
+			t.Errorf("Expected the ingress annotation, got %v", ing.Annotations)
+		}
+	}
+
+	// the Kubernetes versions before 1.19 get an extensions/v1beta1 Ingress
+	objs, err = k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, KubeVersion: "1.18"})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.Transform failed"))
+	}
+	found := false
+	for _, obj := range objs {
+		ing, ok := obj.(*networkingv1beta1.Ingress)
+		if !ok {
+			continue
+		}
+		found = true
+		if ing.APIVersion != "extensions/v1beta1" {
+			t.Errorf("Expected extensions/v1beta1, got %s", ing.APIVersion)
+		}
+		if ing.Annotations["kubernetes.io/ingress.class"] != "nginx" {
+			t.Errorf("Expected the ingress class annotation, got %v", ing.Annotations)
+		}
+		if len(ing.Spec.TLS) != 2 || ing.Spec.TLS[1].SecretName != "b-tls" {
+			t.Errorf("Expected the TLS secret of each host, got %#v", ing.Spec.TLS)
+		}
+	}
+	if !found {
+		t.Errorf("Expected an extensions/v1beta1 Ingress")
 	}
 }
 
@@ -284,8 +369,10 @@ func TestKomposeConvert(t *testing.T) {
 		"Convert to Deployments (D) with v3 replicas": {newKomposeObject(), kobject.ConvertOptions{CreateD: true}, 5},
 		"Convert to DaemonSets (DS)":                  {newKomposeObject(), kobject.ConvertOptions{CreateDS: true}, 5},
 		// objects generated are deployment, daemonset, ReplicationController, service and pvc
-		"Convert to D, DS, and RC":                  {newKomposeObject(), kobject.ConvertOptions{CreateD: true, CreateDS: true, CreateRC: true, Replicas: replicas, IsReplicaSetFlag: true}, 6},
-		"Convert to D, DS, and RC with v3 replicas": {newKomposeObject(), kobject.ConvertOptions{CreateD: true, CreateDS: true, CreateRC: true}, 6},
+		"Convert to ReplicationController (RC)":     {newKomposeObject(), kobject.ConvertOptions{CreateRC: true, Replicas: replicas, IsReplicaSetFlag: true}, 5},
+		"Convert to RC with --controller":           {newKomposeObject(), kobject.ConvertOptions{Controller: ReplicationController}, 5},
+		"Convert to D, DS, and RC":                  {newKomposeObject(), kobject.ConvertOptions{CreateD: true, CreateDS: true, CreateRC: true, Replicas: replicas, IsReplicaSetFlag: true}, 7},
+		"Convert to D, DS, and RC with v3 replicas": {newKomposeObject(), kobject.ConvertOptions{CreateD: true, CreateDS: true, CreateRC: true}, 7},
 		// TODO: add more tests
 	}
 
@@ -301,7 +388,7 @@ func TestKomposeConvert(t *testing.T) {
 			t.Errorf("Expected %d objects returned, got %d", test.expectedNumObjs, len(objs))
 		}
 
-		var foundSVC, foundD, foundDS, foundDC bool
+		var foundSVC, foundD, foundDS, foundRC, foundDC bool
 		name := "app"
 		labels := transformer.ConfigLabels(name)
 		config := test.komposeObject.ServiceConfigs[name]
@@ -409,6 +496,28 @@ func TestKomposeConvert(t *testing.T) {
 				}
 			}
 
+			if test.opt.CreateRC || test.opt.Controller == ReplicationController {
+				if rc, ok := obj.(*api.ReplicationController); ok {
+					if err := checkPodTemplate(config, *rc.Spec.Template, labelsWithNetwork); err != nil {
+						t.Errorf("%v", err)
+					}
+					if err := checkMeta(config, rc.ObjectMeta, name, true); err != nil {
+						t.Errorf("%v", err)
+					}
+					if !equalStringMaps(labels, rc.Spec.Selector) {
+						t.Errorf("Found unexpected selector: %#v vs. %#v", labels, rc.Spec.Selector)
+					}
+					expectedReplicas := newServiceConfig().Replicas
+					if test.opt.IsReplicaSetFlag {
+						expectedReplicas = replicas
+					}
+					if (int)(*rc.Spec.Replicas) != expectedReplicas {
+						t.Errorf("Expected %d replicas, got %d", expectedReplicas, *rc.Spec.Replicas)
+					}
+					foundRC = true
+				}
+			}
+
 			// TODO: k8s & openshift transformer is now separated; either separate the test or combine the transformer
 			if test.opt.CreateDeploymentConfig {
 				if dc, ok := obj.(*deployapi.DeploymentConfig); ok {
@@ -437,6 +546,9 @@ func TestKomposeConvert(t *testing.T) {
 		if test.opt.CreateDS != foundDS {
 			t.Errorf("Expected create Daemon Set: %v, found Daemon Set: %v", test.opt.CreateDS, foundDS)
 		}
+		if (test.opt.CreateRC || test.opt.Controller == ReplicationController) != foundRC {
+			t.Errorf("Expected create Replication Controller: %v, found Replication Controller: %v", test.opt.CreateRC, foundRC)
+		}
 
 		if test.opt.CreateDeploymentConfig != foundDC {
 			t.Errorf("Expected create Deployment Config: %v, found Deployment Config: %v", test.opt.CreateDeploymentConfig, foundDC)
@@ -639,3 +751,965 @@ func TestMultipleContainersInPod(t *testing.T) {
 		}
 	}
 }
+
+func TestKomposeConvertStatefulSet(t *testing.T) {
+	testCases := map[string]struct {
+		komposeObject kobject.KomposeObject
+		opt           kobject.ConvertOptions
+	}{
+		"Convert to StatefulSet with --controller": {newKomposeObject(), kobject.ConvertOptions{Controller: StatefulSetController}},
+		"Convert to StatefulSet with label": {
+			kobject.KomposeObject{
+				ServiceConfigs: map[string]kobject.ServiceConfig{"app": func() kobject.ServiceConfig {
+					config := newServiceConfig()
+					config.Labels = map[string]string{compose.LabelControllerType: "statefulset"}
+					return config
+				}()},
+			}, kobject.ConvertOptions{CreateD: true}},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		k := Kubernetes{}
+		objs, err := k.Transform(test.komposeObject, test.opt)
+		if err != nil {
+			t.Error(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		var foundSS, foundSVC bool
+		for _, obj := range objs {
+			switch o := obj.(type) {
+			case *appsv1.StatefulSet:
+				foundSS = true
+				if o.Spec.ServiceName != "app" {
+					t.Errorf("Expected governing service app, got %q", o.Spec.ServiceName)
+				}
+				if len(o.Spec.VolumeClaimTemplates) != 1 || o.Spec.VolumeClaimTemplates[0].Name != "app-claim0" {
+					t.Errorf("Expected volumeClaimTemplates [app-claim0], got %#v", o.Spec.VolumeClaimTemplates)
+				}
+				for _, v := range o.Spec.Template.Spec.Volumes {
+					if v.PersistentVolumeClaim != nil {
+						t.Errorf("Expected no claim in pod volumes, got %#v", v)
+					}
+				}
+				if o.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name != "app-claim0" {
+					t.Errorf("Expected volume mount app-claim0, got %q", o.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name)
+				}
+			case *api.Service:
+				foundSVC = true
+				if o.Spec.ClusterIP != "None" {
+					t.Errorf("Expected headless governing service, got clusterIP %q", o.Spec.ClusterIP)
+				}
+			case *api.PersistentVolumeClaim:
+				t.Errorf("Expected no standalone PersistentVolumeClaim, got %s", o.Name)
+			case *appsv1.Deployment:
+				t.Errorf("Expected no Deployment, got %s", o.Name)
+			}
+		}
+		if !foundSS {
+			t.Errorf("StatefulSet not created")
+		}
+		if !foundSVC {
+			t.Errorf("Governing Service not created")
+		}
+	}
+}
+
+func TestKomposeConvertJob(t *testing.T) {
+	maxAttempts := uint64(3)
+
+	testCases := map[string]struct {
+		service       kobject.ServiceConfig
+		opt           kobject.ConvertOptions
+		cronJob       bool
+		restartPolicy api.RestartPolicy
+		backoffLimit  *int32
+	}{
+		"Convert 'restart: no' to Job with --controller": {
+			kobject.ServiceConfig{Image: "foobar", Restart: "no"},
+			kobject.ConvertOptions{Controller: JobController}, false, api.RestartPolicyNever, func() *int32 { i := int32(0); return &i }(),
+		},
+		"Convert 'restart: always' to Job with label": {
+			kobject.ServiceConfig{Image: "foobar", Restart: "always", Labels: map[string]string{compose.LabelControllerType: "job"}},
+			kobject.ConvertOptions{CreateD: true}, false, api.RestartPolicyOnFailure, nil,
+		},
+		"Convert scheduled service to CronJob": {
+			kobject.ServiceConfig{Image: "foobar", Restart: "on-failure", RestartMaxAttempts: &maxAttempts, CronJobSchedule: "*/5 * * * *"},
+			kobject.ConvertOptions{CreateD: true}, true, api.RestartPolicyOnFailure, func() *int32 { i := int32(3); return &i }(),
+		},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		k := Kubernetes{}
+		objs, err := k.Transform(kobject.KomposeObject{ServiceConfigs: map[string]kobject.ServiceConfig{"app": test.service}}, test.opt)
+		if err != nil {
+			t.Error(errors.Wrap(err, "k.Transform failed"))
+		}
+		if len(objs) != 1 {
+			t.Errorf("Expected only one object, got %d", len(objs))
+		}
+
+		var spec batchv1.JobSpec
+		switch o := objs[0].(type) {
+		case *batchv1.Job:
+			if test.cronJob {
+				t.Errorf("Expected CronJob, got Job")
+			}
+			spec = o.Spec
+		case *batchv1.CronJob:
+			if !test.cronJob {
+				t.Errorf("Expected Job, got CronJob")
+			}
+			if o.Spec.Schedule != test.service.CronJobSchedule {
+				t.Errorf("Expected schedule %q, got %q", test.service.CronJobSchedule, o.Spec.Schedule)
+			}
+			spec = o.Spec.JobTemplate.Spec
+		default:
+			t.Fatalf("Expected Job or CronJob, got %T", o)
+		}
+
+		if spec.Template.Spec.RestartPolicy != test.restartPolicy {
+			t.Errorf("Expected restartPolicy %s, got %s", test.restartPolicy, spec.Template.Spec.RestartPolicy)
+		}
+		if spec.Template.Spec.Containers[0].Image != "foobar" {
+			t.Errorf("Expected image foobar, got %s", spec.Template.Spec.Containers[0].Image)
+		}
+		if !reflect.DeepEqual(spec.BackoffLimit, test.backoffLimit) {
+			t.Errorf("Expected backoffLimit %v, got %v", test.backoffLimit, spec.BackoffLimit)
+		}
+	}
+}
+
+func TestWaitForDependencies(t *testing.T) {
+	web := kobject.ServiceConfig{
+		Image:     "web",
+		Port:      []kobject.Ports{{HostPort: 80, ContainerPort: 8080, Protocol: api.ProtocolTCP}},
+		DependsOn: map[string]string{"db": compose.DependsOnServiceHealthy, "cache": compose.DependsOnServiceStarted, "backend": compose.DependsOnServiceHealthy},
+	}
+	backend := kobject.ServiceConfig{
+		Image:        "backend",
+		Port:         []kobject.Ports{{ContainerPort: 8000, Protocol: api.ProtocolTCP}},
+		HealthChecks: kobject.HealthChecks{Liveness: kobject.HealthCheck{HTTPPath: "/health", HTTPPort: 8000}},
+	}
+	db := kobject.ServiceConfig{
+		Image:        "postgres",
+		Port:         []kobject.Ports{{ContainerPort: 5432, Protocol: api.ProtocolTCP}},
+		HealthChecks: kobject.HealthChecks{Liveness: kobject.HealthCheck{Test: []string{"pg_isready"}}},
+	}
+	cache := kobject.ServiceConfig{
+		Image: "redis",
+		Port:  []kobject.Ports{{ContainerPort: 6379, Protocol: api.ProtocolTCP}},
+	}
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{"web": web, "backend": backend, "db": db, "cache": cache},
+	}
+
+	k := Kubernetes{}
+	objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, WaitForDependencies: true, WaitForDependenciesImage: "registry.local/busybox"})
+	if err != nil {
+		t.Error(errors.Wrap(err, "k.Transform failed"))
+	}
+
+	for _, obj := range objs {
+		d, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+		initContainers := d.Spec.Template.Spec.InitContainers
+		switch d.Name {
+		case "web":
+			if len(initContainers) != 3 {
+				t.Fatalf("Expected 3 init containers, got %d", len(initContainers))
+			}
+			expected := map[string]string{
+				"wait-for-backend": "until nc -z -w 2 backend 8000; do echo waiting for backend; sleep 2; done",
+				"wait-for-cache":   "until nc -z -w 2 cache 6379; do echo waiting for cache; sleep 2; done",
+				"wait-for-db":      "until nc -z -w 2 db 5432; do echo waiting for db; sleep 2; done",
+			}
+			for _, c := range initContainers {
+				if c.Command[2] != expected[c.Name] {
+					t.Errorf("Expected init container %s to run %q, got %q", c.Name, expected[c.Name], c.Command[2])
+				}
+				if c.Image != "registry.local/busybox" {
+					t.Errorf("Expected init container %s to run registry.local/busybox, got %s", c.Name, c.Image)
+				}
+			}
+		case "db":
+			if len(initContainers) != 0 {
+				t.Errorf("Expected no init container, got %d", len(initContainers))
+			}
+			probe := d.Spec.Template.Spec.Containers[0].ReadinessProbe
+			if probe == nil || !reflect.DeepEqual(probe.Exec.Command, []string{"pg_isready"}) {
+				t.Errorf("Expected readiness probe from the healthcheck, got %#v", probe)
+			}
+		case "backend":
+			probe := d.Spec.Template.Spec.Containers[0].ReadinessProbe
+			if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Path != "/health" || probe.HTTPGet.Port.IntValue() != 8000 {
+				t.Errorf("Expected HTTP readiness probe from the healthcheck, got %#v", probe)
+			}
+		case "cache":
+			if d.Spec.Template.Spec.Containers[0].ReadinessProbe != nil {
+				t.Errorf("Expected no readiness probe for cache")
+			}
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	testCases := map[string]struct {
+		projectName       string
+		opt               kobject.ConvertOptions
+		expectedNamespace string
+		expectedObject    bool
+	}{
+		"No namespace":                     {"", kobject.ConvertOptions{}, "", false},
+		"Namespace from the project name":  {"My_Project", kobject.ConvertOptions{}, "my-project", false},
+		"Namespace option":                 {"project", kobject.ConvertOptions{Namespace: "staging"}, "staging", false},
+		"Namespace flag set to empty":      {"project", kobject.ConvertOptions{IsNamespaceFlag: true}, "", false},
+		"Create namespace":                 {"", kobject.ConvertOptions{Namespace: "staging", CreateNamespace: true}, "staging", true},
+		"Create namespace without a name":  {"", kobject.ConvertOptions{CreateNamespace: true}, "", false},
+		"Create namespace of project name": {"web", kobject.ConvertOptions{CreateNamespace: true}, "web", true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := newKomposeObject()
+		komposeObject.Name = test.projectName
+		test.opt.CreateD = true
+		test.opt.Replicas = 1
+
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, test.opt)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		if ns, ok := objs[0].(*api.Namespace); ok != test.expectedObject {
+			t.Errorf("Expected a Namespace first: %v, got %T", test.expectedObject, objs[0])
+		} else if ok && ns.Name != test.expectedNamespace {
+			t.Errorf("Expected Namespace %q, got %q", test.expectedNamespace, ns.Name)
+		}
+		for _, obj := range objs {
+			if _, ok := obj.(*api.Namespace); ok {
+				continue
+			}
+			if namespace := obj.(metav1.Object).GetNamespace(); namespace != test.expectedNamespace {
+				t.Errorf("Expected namespace %q on %T, got %q", test.expectedNamespace, obj, namespace)
+			}
+		}
+	}
+}
+
+func TestKubeVersion(t *testing.T) {
+	cron := kobject.ServiceConfig{Image: "foobar", Restart: "on-failure", CronJobSchedule: "*/5 * * * *"}
+	web := newServiceConfig()
+	web.ExposeService = "example.com"
+
+	testCases := map[string]struct {
+		kubeVersion string
+		expected    map[string]string
+		expectedErr string
+	}{
+		"Latest version":  {"", map[string]string{"CronJob": "batch/v1", "Ingress": "networking.k8s.io/v1", "Deployment": "apps/v1"}, ""},
+		"Kubernetes 1.21": {"1.21", map[string]string{"CronJob": "batch/v1", "Ingress": "networking.k8s.io/v1"}, ""},
+		"Kubernetes 1.20": {"v1.20.4", map[string]string{"CronJob": "batch/v1beta1", "Ingress": "networking.k8s.io/v1"}, ""},
+		"Kubernetes 1.18": {"1.18", map[string]string{"CronJob": "batch/v1beta1", "Ingress": "extensions/v1beta1"}, ""},
+		"Kubernetes 1.7":  {"1.7", nil, "CronJob can't be generated for Kubernetes 1.7, it requires batch/v1beta1 from Kubernetes 1.8"},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"cron": cron, "web": web},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, KubeVersion: test.kubeVersion})
+		if test.expectedErr != "" {
+			if err == nil || err.Error() != test.expectedErr {
+				t.Errorf("Expected error %q, got %v", test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+		for _, obj := range objs {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			if expected, ok := test.expected[gvk.Kind]; ok && gvk.GroupVersion().String() != expected {
+				t.Errorf("Expected %s for %s, got %s", expected, gvk.Kind, gvk.GroupVersion().String())
+			}
+		}
+	}
+}
+
+func TestCreateHPA(t *testing.T) {
+	testCases := map[string]struct {
+		service         kobject.ServiceConfig
+		opt             kobject.ConvertOptions
+		expectedMetrics map[api.ResourceName]int32
+		expectedMin     int32
+		expectedErr     string
+	}{
+		"No HPA labels": {
+			kobject.ServiceConfig{Image: "nginx", CPUReservation: 100}, kobject.ConvertOptions{CreateD: true}, nil, 0, "",
+		},
+		"CPU by default": {
+			kobject.ServiceConfig{Image: "nginx", CPUReservation: 100, HPAMaxReplicas: 5}, kobject.ConvertOptions{CreateD: true},
+			map[api.ResourceName]int32{api.ResourceCPU: 80}, 1, "",
+		},
+		"CPU and memory": {
+			kobject.ServiceConfig{Image: "nginx", CPUReservation: 100, MemReservation: 1 << 20, HPAMinReplicas: 2, HPAMaxReplicas: 5, HPACPU: 60, HPAMemory: 70},
+			kobject.ConvertOptions{Controller: StatefulSetController},
+			map[api.ResourceName]int32{api.ResourceCPU: 60, api.ResourceMemory: 70}, 2, "",
+		},
+		"Missing memory request": {
+			kobject.ServiceConfig{Image: "nginx", CPUReservation: 100, HPAMaxReplicas: 5, HPAMemory: 70}, kobject.ConvertOptions{CreateD: true},
+			nil, 0, `service "app" targets a memory utilization of 70% but container "app" has no memory request, set deploy.resources.reservations.memory`,
+		},
+		"DaemonSet": {
+			kobject.ServiceConfig{Image: "nginx", CPUReservation: 100, HPAMaxReplicas: 5}, kobject.ConvertOptions{CreateDS: true},
+			nil, 0, `kompose.hpa.* labels of service "app" require a Deployment or a StatefulSet`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": test.service},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, test.opt)
+		if test.expectedErr != "" {
+			if err == nil || err.Error() != test.expectedErr {
+				t.Errorf("Expected error %q, got %v", test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		var hpa *autoscalingv2.HorizontalPodAutoscaler
+		for _, obj := range objs {
+			if h, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler); ok {
+				hpa = h
+			}
+		}
+		if test.expectedMetrics == nil {
+			if hpa != nil {
+				t.Errorf("Expected no HorizontalPodAutoscaler, got %#v", hpa)
+			}
+			continue
+		}
+		if hpa == nil {
+			t.Fatalf("Expected a HorizontalPodAutoscaler")
+		}
+		if hpa.APIVersion != "autoscaling/v2" || hpa.Spec.ScaleTargetRef.Name != "app" {
+			t.Errorf("Expected an autoscaling/v2 HorizontalPodAutoscaler of app, got %s of %s", hpa.APIVersion, hpa.Spec.ScaleTargetRef.Name)
+		}
+		if *hpa.Spec.MinReplicas != test.expectedMin || hpa.Spec.MaxReplicas != test.service.HPAMaxReplicas {
+			t.Errorf("Expected %d to %d replicas, got %d to %d", test.expectedMin, test.service.HPAMaxReplicas, *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+		}
+		metrics := map[api.ResourceName]int32{}
+		for _, m := range hpa.Spec.Metrics {
+			metrics[m.Resource.Name] = *m.Resource.Target.AverageUtilization
+		}
+		if !reflect.DeepEqual(metrics, test.expectedMetrics) {
+			t.Errorf("Expected metrics %v, got %v", test.expectedMetrics, metrics)
+		}
+	}
+}
+
+func TestCreatePDB(t *testing.T) {
+	parallelism := uint64(2)
+	testCases := map[string]struct {
+		service                kobject.ServiceConfig
+		opt                    kobject.ConvertOptions
+		expectedMaxUnavailable string
+		expectedMinAvailable   string
+	}{
+		"Not requested":     {kobject.ServiceConfig{Image: "nginx", Replicas: 3}, kobject.ConvertOptions{CreateD: true}, "", ""},
+		"Single replica":    {kobject.ServiceConfig{Image: "nginx", Replicas: 1}, kobject.ConvertOptions{CreateD: true, CreatePDB: true}, "", ""},
+		"Default":           {kobject.ServiceConfig{Image: "nginx", Replicas: 3}, kobject.ConvertOptions{CreateD: true, CreatePDB: true}, "1", ""},
+		"From parallelism":  {kobject.ServiceConfig{Image: "nginx", Replicas: 3, DeployUpdateConfig: dockerCliTypes.UpdateConfig{Parallelism: &parallelism}}, kobject.ConvertOptions{Controller: StatefulSetController, CreatePDB: true}, "2", ""},
+		"Label overrides":   {kobject.ServiceConfig{Image: "nginx", Replicas: 3, PDBMinAvailable: "50%"}, kobject.ConvertOptions{CreateD: true, CreatePDB: true}, "", "50%"},
+		"Label without opt": {kobject.ServiceConfig{Image: "nginx", Replicas: 3, PDBMinAvailable: "2"}, kobject.ConvertOptions{CreateD: true}, "", "2"},
+		"DaemonSet":         {kobject.ServiceConfig{Image: "nginx", Replicas: 3}, kobject.ConvertOptions{CreateDS: true, CreatePDB: true}, "", ""},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": test.service},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, test.opt)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		var pdb *policyv1.PodDisruptionBudget
+		for _, obj := range objs {
+			if p, ok := obj.(*policyv1.PodDisruptionBudget); ok {
+				pdb = p
+			}
+		}
+		if test.expectedMaxUnavailable == "" && test.expectedMinAvailable == "" {
+			if pdb != nil {
+				t.Errorf("Expected no PodDisruptionBudget, got %#v", pdb)
+			}
+			continue
+		}
+		if pdb == nil {
+			t.Fatalf("Expected a PodDisruptionBudget")
+		}
+		if pdb.Spec.Selector.MatchLabels[transformer.Selector] != "app" {
+			t.Errorf("Expected the pods of app to be selected, got %v", pdb.Spec.Selector.MatchLabels)
+		}
+		if maxUnavailable := pdb.Spec.MaxUnavailable; (maxUnavailable == nil) != (test.expectedMaxUnavailable == "") || maxUnavailable != nil && maxUnavailable.String() != test.expectedMaxUnavailable {
+			t.Errorf("Expected maxUnavailable %q, got %v", test.expectedMaxUnavailable, maxUnavailable)
+		}
+		if minAvailable := pdb.Spec.MinAvailable; (minAvailable == nil) != (test.expectedMinAvailable == "") || minAvailable != nil && minAvailable.String() != test.expectedMinAvailable {
+			t.Errorf("Expected minAvailable %q, got %v", test.expectedMinAvailable, minAvailable)
+		}
+	}
+}
+
+func TestHostAliasesAndDNSConfig(t *testing.T) {
+	ndots := "2"
+	service := kobject.ServiceConfig{
+		Image:      "nginx",
+		ExtraHosts: []string{"somehost:162.242.195.82", "otherhost=162.242.195.82", "ipv6host:::1", "invalid"},
+		DNS:        []string{"8.8.8.8"},
+		DNSSearch:  []string{"example.com"},
+		DNSOpts:    []string{"ndots:2", "use-vc"},
+	}
+	expectedHostAliases := []api.HostAlias{
+		{IP: "162.242.195.82", Hostnames: []string{"somehost", "otherhost"}},
+		{IP: "::1", Hostnames: []string{"ipv6host"}},
+	}
+	expectedDNSConfig := &api.PodDNSConfig{
+		Nameservers: []string{"8.8.8.8"},
+		Searches:    []string{"example.com"},
+		Options:     []api.PodDNSConfigOption{{Name: "ndots", Value: &ndots}, {Name: "use-vc"}},
+	}
+
+	for _, multipleContainerMode := range []bool{false, true} {
+		t.Log("Multiple container mode:", multipleContainerMode)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": service},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, MultipleContainerMode: multipleContainerMode})
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		for _, obj := range objs {
+			deployment, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			spec := deployment.Spec.Template.Spec
+			if !reflect.DeepEqual(spec.HostAliases, expectedHostAliases) {
+				t.Errorf("Expected host aliases %v, got %v", expectedHostAliases, spec.HostAliases)
+			}
+			if !reflect.DeepEqual(spec.DNSConfig, expectedDNSConfig) {
+				t.Errorf("Expected DNS config %v, got %v", expectedDNSConfig, spec.DNSConfig)
+			}
+			if spec.DNSPolicy != api.DNSNone {
+				t.Errorf("Expected DNS policy %q, got %q", api.DNSNone, spec.DNSPolicy)
+			}
+		}
+	}
+
+	// search domains and options alone keep the cluster DNS
+	policy, dnsConfig := GetDNSConfig("app", kobject.ServiceConfig{DNSSearch: []string{"example.com"}}, nil)
+	if policy != "" || dnsConfig == nil || !reflect.DeepEqual(dnsConfig.Searches, []string{"example.com"}) {
+		t.Errorf("Expected the search domains without DNS policy, got %q and %v", policy, dnsConfig)
+	}
+}
+
+func TestSysctlsShmSizeAndReadOnly(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Image:    "postgres",
+		Sysctls:  map[string]string{"net.core.somaxconn": "1024", "net.ipv4.tcp_syncookies": "0"},
+		ShmSize:  268435456,
+		ReadOnly: true,
+	}
+	expectedSysctls := []api.Sysctl{{Name: "net.core.somaxconn", Value: "1024"}, {Name: "net.ipv4.tcp_syncookies", Value: "0"}}
+	shmSize := resource.MustParse("256Mi")
+
+	for _, multipleContainerMode := range []bool{false, true} {
+		t.Log("Multiple container mode:", multipleContainerMode)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"db": service},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, MultipleContainerMode: multipleContainerMode})
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		found := false
+		for _, obj := range objs {
+			deployment, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			found = true
+			spec := deployment.Spec.Template.Spec
+			if spec.SecurityContext == nil || !reflect.DeepEqual(spec.SecurityContext.Sysctls, expectedSysctls) {
+				t.Errorf("Expected sysctls %v, got %v", expectedSysctls, spec.SecurityContext)
+			}
+			if securityContext := spec.Containers[0].SecurityContext; securityContext == nil || securityContext.ReadOnlyRootFilesystem == nil || !*securityContext.ReadOnlyRootFilesystem {
+				t.Errorf("Expected a read-only root filesystem, got %v", securityContext)
+			}
+			if len(spec.Volumes) != 1 || spec.Volumes[0].EmptyDir == nil || spec.Volumes[0].EmptyDir.Medium != api.StorageMediumMemory ||
+				spec.Volumes[0].EmptyDir.SizeLimit == nil || spec.Volumes[0].EmptyDir.SizeLimit.Cmp(shmSize) != 0 {
+				t.Errorf("Expected a memory emptyDir of %s, got %v", shmSize.String(), spec.Volumes)
+			}
+			if mounts := spec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/dev/shm" {
+				t.Errorf("Expected /dev/shm to be mounted, got %v", mounts)
+			}
+		}
+		if !found {
+			t.Errorf("Expected a Deployment")
+		}
+	}
+}
+
+func TestSecurityOpts(t *testing.T) {
+	allowPrivilegeEscalation := false
+	profile := "audit.json"
+	testCases := map[string]struct {
+		securityOpt []string
+		expected    *api.SecurityContext
+	}{
+		"No new privileges":   {[]string{"no-new-privileges:true"}, &api.SecurityContext{AllowPrivilegeEscalation: &allowPrivilegeEscalation}},
+		"Seccomp unconfined":  {[]string{"seccomp=unconfined"}, &api.SecurityContext{SeccompProfile: &api.SeccompProfile{Type: api.SeccompProfileTypeUnconfined}}},
+		"Seccomp localhost":   {[]string{"seccomp:/etc/docker/seccomp/audit.json"}, &api.SecurityContext{SeccompProfile: &api.SeccompProfile{Type: api.SeccompProfileTypeLocalhost, LocalhostProfile: &profile}}},
+		"SELinux labels":      {[]string{"label:type:svirt_apache_t", "label=level:s0:c100,c200"}, &api.SecurityContext{SELinuxOptions: &api.SELinuxOptions{Type: "svirt_apache_t", Level: "s0:c100,c200"}}},
+		"Unsupported options": {[]string{"label:disable", "systempaths=unconfined"}, nil},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": {Image: "nginx", SecurityOpt: test.securityOpt}},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1})
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+		for _, obj := range objs {
+			if deployment, ok := obj.(*appsv1.Deployment); ok {
+				if securityContext := deployment.Spec.Template.Spec.Containers[0].SecurityContext; !reflect.DeepEqual(securityContext, test.expected) {
+					t.Errorf("Expected security context %v, got %v", test.expected, securityContext)
+				}
+			}
+		}
+	}
+}
+
+func TestAppArmorProfile(t *testing.T) {
+	testCases := map[string]struct {
+		kubeVersion         string
+		multipleContainer   bool
+		expectedAnnotation  string
+		expectedFieldExists bool
+	}{
+		"Annotation before 1.30":                {"1.29", false, "localhost/my-profile", false},
+		"Field from 1.30":                       {"1.30", false, "", true},
+		"Field for the latest version":          {"", false, "", true},
+		"Annotation in multiple container mode": {"1.25", true, "localhost/my-profile", false},
+		"Field in multiple container mode":      {"", true, "", true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": {Name: "app", Image: "nginx", SecurityOpt: []string{"apparmor=my-profile"}}},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, KubeVersion: test.kubeVersion, MultipleContainerMode: test.multipleContainer})
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		for _, obj := range objs {
+			switch o := obj.(type) {
+			case *appsv1.Deployment:
+				if test.expectedFieldExists {
+					t.Errorf("Expected an unstructured Deployment with the appArmorProfile field")
+				}
+				if annotation := o.Spec.Template.Annotations[AppArmorAnnotationPrefix+"app"]; annotation != test.expectedAnnotation {
+					t.Errorf("Expected the AppArmor annotation %q, got %q", test.expectedAnnotation, annotation)
+				}
+			case *unstructured.Unstructured:
+				if !test.expectedFieldExists {
+					t.Errorf("Expected a Deployment with the AppArmor annotation, got %v", o)
+				}
+				containers, _, _ := unstructured.NestedSlice(o.Object, "spec", "template", "spec", "containers")
+				profile, _, _ := unstructured.NestedStringMap(containers[0].(map[string]interface{}), "securityContext", "appArmorProfile")
+				if expected := map[string]string{"type": "Localhost", "localhostProfile": "my-profile"}; !reflect.DeepEqual(profile, expected) {
+					t.Errorf("Expected the AppArmor profile %v, got %v", expected, profile)
+				}
+				if annotations, _, _ := unstructured.NestedStringMap(o.Object, "spec", "template", "metadata", "annotations"); annotations[AppArmorAnnotationPrefix+"app"] != "" {
+					t.Errorf("Expected the AppArmor annotation to be removed, got %v", annotations)
+				}
+			}
+		}
+	}
+}
+
+func TestAppArmorProfileMultipleContainers(t *testing.T) {
+	uid := int64(1000)
+	labels := map[string]string{compose.LabelServiceGroup: "app"}
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web":    {Name: "web", Image: "nginx", Labels: labels},
+			"worker": {Name: "worker", Image: "busybox", Labels: labels, User: "1000", SecurityOpt: []string{"apparmor=my-profile"}},
+		},
+	}
+	k := Kubernetes{}
+	objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1, KubeVersion: "1.29", MultipleContainerMode: true})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.Transform failed"))
+	}
+
+	for _, obj := range objs {
+		deployment, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+		annotations := deployment.Spec.Template.Annotations
+		if annotation := annotations[AppArmorAnnotationPrefix+"worker"]; annotation != "localhost/my-profile" {
+			t.Errorf("Expected the AppArmor annotation of the worker container, got %v", annotations)
+		}
+		if annotation, ok := annotations[AppArmorAnnotationPrefix+"web"]; ok {
+			t.Errorf("Expected no AppArmor annotation for the web container, got %q", annotation)
+		}
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			switch container.Name {
+			case "web":
+				if container.SecurityContext != nil {
+					t.Errorf("Expected no security context for the web container, got %v", container.SecurityContext)
+				}
+			case "worker":
+				if container.SecurityContext == nil || !reflect.DeepEqual(container.SecurityContext.RunAsUser, &uid) {
+					t.Errorf("Expected the worker container to run as user %d, got %v", uid, container.SecurityContext)
+				}
+			}
+		}
+	}
+}
+
+func TestDeviceResources(t *testing.T) {
+	testCases := map[string]struct {
+		service          kobject.ServiceConfig
+		opt              kobject.ConvertOptions
+		expectedLimits   api.ResourceList
+		expectedHostPath string
+	}{
+		"GPUs": {
+			kobject.ServiceConfig{Name: "app", Image: "inference", GPUs: 2, MemLimit: 1073741824},
+			kobject.ConvertOptions{CreateD: true, Replicas: 1},
+			api.ResourceList{"nvidia.com/gpu": resource.MustParse("2"), api.ResourceMemory: resource.MustParse("1Gi")},
+			"",
+		},
+		"GPU vendor": {
+			kobject.ServiceConfig{Name: "app", Image: "inference", GPUs: 1},
+			kobject.ConvertOptions{CreateD: true, Replicas: 1, GPUResource: "amd.com/gpu"},
+			api.ResourceList{"amd.com/gpu": resource.MustParse("1")},
+			"",
+		},
+		"GPUs in multiple container mode": {
+			kobject.ServiceConfig{Name: "app", Image: "inference", GPUs: 1},
+			kobject.ConvertOptions{CreateD: true, Replicas: 1, MultipleContainerMode: true},
+			api.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+			"",
+		},
+		"Host devices": {
+			kobject.ServiceConfig{Name: "app", Image: "reader", Devices: []string{"/dev/ttyUSB0:/dev/ttyACM0:rwm"}},
+			kobject.ConvertOptions{CreateD: true, Replicas: 1},
+			nil,
+			"/dev/ttyUSB0",
+		},
+		"Device plugin resource": {
+			kobject.ServiceConfig{Name: "app", Image: "reader", Devices: []string{"/dev/ttyUSB0", "/dev/ttyUSB1"}, DevicesResource: "squat.ai/serial"},
+			kobject.ConvertOptions{CreateD: true, Replicas: 1, MultipleContainerMode: true},
+			api.ResourceList{"squat.ai/serial": resource.MustParse("2")},
+			"",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": test.service},
+		}
+		k := Kubernetes{}
+		objs, err := k.Transform(komposeObject, test.opt)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "k.Transform failed"))
+		}
+
+		for _, obj := range objs {
+			deployment, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			spec := deployment.Spec.Template.Spec
+			limits := spec.Containers[0].Resources.Limits
+			if len(limits) != len(test.expectedLimits) {
+				t.Errorf("Expected limits %v, got %v", test.expectedLimits, limits)
+			}
+			for resourceName, expected := range test.expectedLimits {
+				if quantity, ok := limits[resourceName]; !ok || quantity.Cmp(expected) != 0 {
+					t.Errorf("Expected %s limit %s, got %v", resourceName, expected.String(), limits)
+				}
+			}
+			if test.expectedHostPath == "" {
+				if len(spec.Volumes) != 0 {
+					t.Errorf("Expected no volume, got %v", spec.Volumes)
+				}
+				continue
+			}
+			if len(spec.Volumes) != 1 || spec.Volumes[0].HostPath == nil || spec.Volumes[0].HostPath.Path != test.expectedHostPath {
+				t.Errorf("Expected a hostPath volume of %s, got %v", test.expectedHostPath, spec.Volumes)
+			}
+			if mounts := spec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/dev/ttyACM0" {
+				t.Errorf("Expected the device to be mounted at /dev/ttyACM0, got %v", mounts)
+			}
+		}
+	}
+}
+
+func TestConfigVolumes(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Name:  "app",
+		Image: "nginx",
+		Volumes: []kobject.Volumes{
+			{SvcName: "app", VolumeName: "data", Container: "/data", StorageClass: "efs", VolumeMode: "Filesystem", AccessMode: "ReadWriteMany"},
+			{SvcName: "app", VolumeName: "legacy", Container: "/legacy", External: true, ClaimName: "legacy-claim"},
+			{SvcName: "app", VolumeName: "exports", Container: "/exports", Mode: "ro", NFSServer: "10.0.0.5", NFSPath: "/exports/data"},
+		},
+	}
+
+	k := Kubernetes{}
+	_, volumes, pvcs, _, err := k.ConfigVolumes("app", service)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.ConfigVolumes failed"))
+	}
+
+	if len(pvcs) != 1 || pvcs[0].Name != "data" {
+		t.Fatalf("Expected a single PVC data, got %#v", pvcs)
+	}
+	spec := pvcs[0].Spec
+	if spec.StorageClassName == nil || *spec.StorageClassName != "efs" {
+		t.Errorf("Expected storageClassName efs, got %v", spec.StorageClassName)
+	}
+	if spec.VolumeMode == nil || *spec.VolumeMode != api.PersistentVolumeFilesystem {
+		t.Errorf("Expected volumeMode Filesystem, got %v", spec.VolumeMode)
+	}
+	if !reflect.DeepEqual(spec.AccessModes, []api.PersistentVolumeAccessMode{api.ReadWriteMany}) {
+		t.Errorf("Expected access modes [ReadWriteMany], got %v", spec.AccessModes)
+	}
+
+	if len(volumes) != 3 {
+		t.Fatalf("Expected 3 volumes, got %d", len(volumes))
+	}
+	if claim := volumes[1].PersistentVolumeClaim; claim == nil || claim.ClaimName != "legacy-claim" {
+		t.Errorf("Expected volume legacy to use the claim legacy-claim, got %#v", volumes[1].VolumeSource)
+	}
+	expectedNFS := &api.NFSVolumeSource{Server: "10.0.0.5", Path: "/exports/data", ReadOnly: true}
+	if !reflect.DeepEqual(volumes[2].NFS, expectedNFS) {
+		t.Errorf("Expected NFS volume source %#v, got %#v", expectedNFS, volumes[2].VolumeSource)
+	}
+}
+
+func TestCreateNamedVolumePVCs(t *testing.T) {
+	shared := kobject.Volumes{VolumeName: "data", Container: "/data", AccessMode: "ReadWriteMany", Shared: true}
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web":    {Name: "web", Image: "nginx", Volumes: []kobject.Volumes{shared}},
+			"worker": {Name: "worker", Image: "busybox", Volumes: []kobject.Volumes{shared}},
+		},
+		NamedVolumes: map[string]kobject.NamedVolume{
+			"data":  {Name: "data", AccessMode: "ReadWriteMany", Consumers: []string{"web", "worker"}},
+			"cache": {Name: "cache", Consumers: []string{"web"}},
+		},
+	}
+
+	k := Kubernetes{}
+	objs, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.Transform failed"))
+	}
+
+	var pvcs []*api.PersistentVolumeClaim
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *api.PersistentVolumeClaim:
+			pvcs = append(pvcs, o)
+		case *appsv1.Deployment:
+			volumes := o.Spec.Template.Spec.Volumes
+			if len(volumes) != 1 || volumes[0].PersistentVolumeClaim == nil || volumes[0].PersistentVolumeClaim.ClaimName != "data" {
+				t.Errorf("Expected %s to mount the claim data, got %#v", o.Name, volumes)
+			}
+		}
+	}
+	if len(pvcs) != 1 || pvcs[0].Name != "data" {
+		t.Fatalf("Expected a single PVC data, got %#v", pvcs)
+	}
+	if !reflect.DeepEqual(pvcs[0].Spec.AccessModes, []api.PersistentVolumeAccessMode{api.ReadWriteMany}) {
+		t.Errorf("Expected access modes [ReadWriteMany], got %v", pvcs[0].Spec.AccessModes)
+	}
+
+	testCases := map[string]struct {
+		opt      kobject.ConvertOptions
+		replicas int
+		expected bool
+	}{
+		"Separate pods":           {kobject.ConvertOptions{Replicas: 1}, 1, true},
+		"Same pod":                {kobject.ConvertOptions{Replicas: 1, MultipleContainerMode: true}, 1, false},
+		"Same pod, with replicas": {kobject.ConvertOptions{Replicas: 1, MultipleContainerMode: true}, 2, true},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		grouped := kobject.KomposeObject{ServiceConfigs: map[string]kobject.ServiceConfig{}}
+		for _, service := range []string{"web", "worker"} {
+			grouped.ServiceConfigs[service] = kobject.ServiceConfig{Labels: map[string]string{compose.LabelServiceGroup: "app"}, Replicas: test.replicas}
+		}
+		volume := kobject.NamedVolume{Name: "data", Consumers: []string{"web", "worker"}}
+		if spans := volumeSpansPods(volume, grouped, test.opt); spans != test.expected {
+			t.Errorf("Expected volumeSpansPods %v, got %v", test.expected, spans)
+		}
+	}
+}
+
+func TestConfigSecretVolumes(t *testing.T) {
+	mode := uint32(0400)
+	service := kobject.ServiceConfig{
+		Name: "app",
+		Secrets: []dockerCliTypes.ServiceSecretConfig{
+			{Source: "api_token", Target: "/etc/app/token", Mode: &mode, UID: "1000", GID: "1000"},
+			{Source: "tls", Target: "tls"},
+			{Source: "db_password"},
+		},
+		SecretsMetaData: map[string]dockerCliTypes.SecretConfig{
+			"api_token":   {File: "./token.txt"},
+			"tls":         {Name: "tls", External: dockerCliTypes.External{External: true}, Labels: dockerCliTypes.Labels{compose.LabelSecretName: "app-tls"}},
+			"db_password": {Name: "db_password", External: dockerCliTypes.External{External: true}, Labels: dockerCliTypes.Labels{compose.LabelSecretKey: "password"}},
+		},
+	}
+
+	k := Kubernetes{}
+	mounts, volumes := k.ConfigSecretVolumes("app", service)
+
+	expectedMounts := []api.VolumeMount{
+		{Name: "api_token", MountPath: "/etc/app/token", SubPath: "token"},
+		{Name: "tls", MountPath: "/run/secrets/tls"},
+		{Name: "db_password", MountPath: "/run/secrets/db_password"},
+	}
+	if !reflect.DeepEqual(mounts, expectedMounts) {
+		t.Errorf("Expected volume mounts %#v, got %#v", expectedMounts, mounts)
+	}
+
+	itemMode := int32(0400)
+	expectedSources := []*api.SecretVolumeSource{
+		{SecretName: "api-token", Items: []api.KeyToPath{{Key: "api_token", Path: "token", Mode: &itemMode}}},
+		{SecretName: "app-tls"},
+		{SecretName: "db_password", Items: []api.KeyToPath{{Key: "password", Path: "db_password"}}},
+	}
+	for i, expected := range expectedSources {
+		if !reflect.DeepEqual(volumes[i].Secret, expected) {
+			t.Errorf("Expected secret volume source %#v, got %#v", expected, volumes[i].Secret)
+		}
+	}
+
+	if fsGroup := ConfigFSGroup("app", service, nil); fsGroup == nil || *fsGroup != 1000 {
+		t.Errorf("Expected fsGroup 1000, got %v", fsGroup)
+	}
+}
+
+func TestCreateSecrets(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		Secrets: map[string]dockerCliTypes.SecretConfig{
+			"api_token": {},
+			"tls":       {Name: "tls", External: dockerCliTypes.External{External: true}},
+		},
+		SecretsContent: map[string]string{"api_token": "s3cr3t"},
+	}
+
+	k := Kubernetes{}
+	secrets, err := k.CreateSecrets(komposeObject)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.CreateSecrets failed"))
+	}
+	if len(secrets) != 1 || secrets[0].Name != "api-token" {
+		t.Fatalf("Expected a single Secret api-token, got %#v", secrets)
+	}
+	if string(secrets[0].Data["api_token"]) != "s3cr3t" {
+		t.Errorf("Expected the content of the environment variable, got %q", secrets[0].Data["api_token"])
+	}
+}
+
+func TestInitPodSpecWithConfigMap(t *testing.T) {
+	mode := uint32(0440)
+	service := kobject.ServiceConfig{
+		Name: "app",
+		Configs: []dockerCliTypes.ServiceConfigObjConfig{
+			{Source: "nginx_conf", Target: "/etc/nginx/conf.d/default.conf", Mode: &mode, GID: "101"},
+			{Source: "shared"},
+			{Source: "ca", Target: "/etc/ssl/ca.pem"},
+		},
+		ConfigsMetaData: map[string]dockerCliTypes.ConfigObjConfig{
+			"nginx_conf": {},
+			"shared":     {Name: "shared", External: dockerCliTypes.External{External: true}, Labels: dockerCliTypes.Labels{compose.LabelConfigName: "shared-settings"}},
+			"ca":         {Name: "ca", External: dockerCliTypes.External{External: true}, Labels: dockerCliTypes.Labels{compose.LabelConfigKey: "ca.crt"}},
+		},
+	}
+
+	k := Kubernetes{}
+	podSpec := k.InitPodSpecWithConfigMap("app", "nginx", service)
+
+	expectedMounts := []api.VolumeMount{
+		{Name: "nginx-conf", MountPath: "/etc/nginx/conf.d/default.conf", SubPath: "default.conf"},
+		{Name: "shared", MountPath: "/shared"},
+		{Name: "ca", MountPath: "/etc/ssl/ca.pem", SubPath: "ca.pem"},
+	}
+	if !reflect.DeepEqual(podSpec.Containers[0].VolumeMounts, expectedMounts) {
+		t.Errorf("Expected volume mounts %#v, got %#v", expectedMounts, podSpec.Containers[0].VolumeMounts)
+	}
+
+	itemMode := int32(0440)
+	expectedSources := []*api.ConfigMapVolumeSource{
+		{LocalObjectReference: api.LocalObjectReference{Name: "nginx-conf"}, Items: []api.KeyToPath{{Key: "nginx_conf", Path: "default.conf", Mode: &itemMode}}},
+		{LocalObjectReference: api.LocalObjectReference{Name: "shared-settings"}},
+		{LocalObjectReference: api.LocalObjectReference{Name: "ca"}, Items: []api.KeyToPath{{Key: "ca.crt", Path: "ca.pem"}}},
+	}
+	for i, expected := range expectedSources {
+		if !reflect.DeepEqual(podSpec.Volumes[i].ConfigMap, expected) {
+			t.Errorf("Expected ConfigMap volume source %#v, got %#v", expected, podSpec.Volumes[i].ConfigMap)
+		}
+	}
+
+	objects, err := k.createConfigMapFromComposeConfig("app", kobject.ConvertOptions{}, service, map[string]string{"nginx_conf": "server {}"}, nil)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "k.createConfigMapFromComposeConfig failed"))
+	}
+	if len(objects) != 1 {
+		t.Fatalf("Expected a single ConfigMap, got %d objects", len(objects))
+	}
+	if cm, ok := objects[0].(*api.ConfigMap); !ok || cm.Name != "nginx-conf" || cm.Data["nginx_conf"] != "server {}" {
+		t.Errorf("Expected the ConfigMap nginx-conf of the inline content, got %#v", objects[0])
+	}
+
+	if fsGroup := ConfigFSGroup("app", service, nil); fsGroup == nil || *fsGroup != 101 {
+		t.Errorf("Expected fsGroup 101, got %v", fsGroup)
+	}
+}
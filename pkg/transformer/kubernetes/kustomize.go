@@ -0,0 +1,283 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes/kompose/pkg/kobject"
+	"github.com/kubernetes/kompose/pkg/transformer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+const (
+	// FormatKustomize is the output format writing a Kustomize base and its overlays
+	FormatKustomize = "kustomize"
+
+	// KustomizationFile is the name of the file describing a Kustomize base or overlay
+	KustomizationFile = "kustomization.yaml"
+)
+
+// kustomization is the content of a kustomization.yaml file
+type kustomization struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Resources  []string         `json:"resources,omitempty"`
+	Patches    []kustomizePatch `json:"patches,omitempty"`
+}
+
+// kustomizePatch is a patch of a kustomization, the target is given by the patch itself
+type kustomizePatch struct {
+	Path string `json:"path"`
+}
+
+// PrintKustomize writes the objects to the base directory of a Kustomize layout
+// and the objects of each overlay as strategic merge patches against the base,
+// it returns the paths of the files written
+func PrintKustomize(objects []runtime.Object, overlays map[string][]runtime.Object, opt kobject.ConvertOptions) ([]string, error) {
+	dirName := getDirName(opt)
+	baseDir := filepath.Join(dirName, "base")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the Kustomize base directory")
+	}
+
+	var files []string
+	base := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	for _, v := range objects {
+		file, err := printObject(v, baseDir, opt)
+		if err != nil {
+			return nil, err
+		}
+		base.Resources = append(base.Resources, filepath.Base(file))
+		files = append(files, file)
+	}
+	file, err := writeKustomization(baseDir, base, opt)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, file)
+
+	// the overlays are written in a stable order
+	var names []string
+	for name := range overlays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		overlayFiles, err := printOverlay(objects, overlays[name], filepath.Join(dirName, "overlays", name), opt)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to write overlay %s", name)
+		}
+		files = append(files, overlayFiles...)
+	}
+	return files, nil
+}
+
+// printOverlay writes an overlay of the base objects: the objects of the overlay which are in the base
+// are written as strategic merge patches, the other ones as resources of their own,
+// and the base objects missing from the overlay are deleted
+func printOverlay(baseObjects []runtime.Object, objects []runtime.Object, dir string, opt kobject.ConvertOptions) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the Kustomize overlay directory")
+	}
+
+	baseByKey := make(map[string]runtime.Object, len(baseObjects))
+	for _, v := range baseObjects {
+		baseByKey[objectKey(v)] = v
+	}
+
+	var files []string
+	overlay := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{filepath.ToSlash(filepath.Join("..", "..", "base"))},
+	}
+	seen := make(map[string]bool, len(objects))
+	for _, v := range objects {
+		key := objectKey(v)
+		seen[key] = true
+
+		baseObject, ok := baseByKey[key]
+		if !ok {
+			file, err := printObject(v, dir, opt)
+			if err != nil {
+				return nil, err
+			}
+			overlay.Resources = append(overlay.Resources, filepath.Base(file))
+			files = append(files, file)
+			continue
+		}
+
+		patch, err := createPatch(baseObject, v)
+		if err != nil {
+			return nil, err
+		}
+		if patch == nil {
+			continue
+		}
+		file, err := printPatch(v, patch, dir, opt)
+		if err != nil {
+			return nil, err
+		}
+		overlay.Patches = append(overlay.Patches, kustomizePatch{Path: filepath.Base(file)})
+		files = append(files, file)
+	}
+
+	for _, v := range baseObjects {
+		if seen[objectKey(v)] {
+			continue
+		}
+		file, err := printPatch(v, map[string]interface{}{"$patch": "delete"}, dir, opt)
+		if err != nil {
+			return nil, err
+		}
+		overlay.Patches = append(overlay.Patches, kustomizePatch{Path: filepath.Base(file)})
+		files = append(files, file)
+	}
+
+	file, err := writeKustomization(dir, overlay, opt)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, file), nil
+}
+
+// createPatch returns the strategic merge patch turning the base object into the overlay one,
+// nil if they are the same. The objects without Go type are patched with the whole overlay object.
+func createPatch(baseObject runtime.Object, object runtime.Object) (map[string]interface{}, error) {
+	original, err := json.Marshal(baseObject)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var patch map[string]interface{}
+	if _, ok := object.(*unstructured.Unstructured); ok {
+		if string(original) == string(modified) {
+			return nil, nil
+		}
+		err = json.Unmarshal(modified, &patch)
+		return patch, err
+	}
+
+	data, err := strategicpatch.CreateTwoWayMergePatch(original, modified, object)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the strategic merge patch")
+	}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	removeOrderDirectives(patch)
+	if len(patch) == 0 {
+		return nil, nil
+	}
+	return patch, nil
+}
+
+// removeOrderDirectives removes the $setElementOrder directives of a strategic merge patch,
+// the order of the lists is kept by the base and the directives are not understood by every Kustomize version
+func removeOrderDirectives(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			if strings.HasPrefix(key, "$setElementOrder/") {
+				delete(v, key)
+				continue
+			}
+			removeOrderDirectives(elem)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			removeOrderDirectives(elem)
+		}
+	}
+}
+
+// printPatch writes a patch of an object, with the kind and the name Kustomize needs to find its target
+func printPatch(v runtime.Object, patch map[string]interface{}, dir string, opt kobject.ConvertOptions) (string, error) {
+	typeMeta, objectMeta := getObjectMeta(v)
+	patch["apiVersion"] = typeMeta.APIVersion
+	patch["kind"] = typeMeta.Kind
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["name"] = objectMeta.Name
+	if objectMeta.Namespace != "" {
+		metadata["namespace"] = objectMeta.Namespace
+	}
+	patch["metadata"] = metadata
+
+	var data []byte
+	var err error
+	if opt.GenerateJSON {
+		data, err = json.MarshalIndent(patch, "", "  ")
+	} else {
+		data, err = marshalWithIndent(patch, opt.YAMLIndent)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "unable to marshal the patch")
+	}
+	return transformer.Print(objectMeta.Name, dir, strings.ToLower(typeMeta.Kind)+"-patch", data, false, opt.GenerateJSON, nil, opt.Provider)
+}
+
+// printObject writes an object to its own file in a directory
+func printObject(v runtime.Object, dir string, opt kobject.ConvertOptions) (string, error) {
+	data, err := marshal(v, opt.GenerateJSON, opt.YAMLIndent)
+	if err != nil {
+		return "", err
+	}
+	typeMeta, objectMeta := getObjectMeta(v)
+	file, err := transformer.Print(objectMeta.Name, dir, strings.ToLower(typeMeta.Kind), data, false, opt.GenerateJSON, nil, opt.Provider)
+	if err != nil {
+		return "", errors.Wrap(err, "transformer.Print failed")
+	}
+	return file, nil
+}
+
+// writeKustomization writes the kustomization.yaml file of a directory
+func writeKustomization(dir string, k kustomization, opt kobject.ConvertOptions) (string, error) {
+	data, err := marshalWithIndent(k, opt.YAMLIndent)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, KustomizationFile)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", file)
+	}
+	log.Printf("Kustomize file %q created", file)
+	return file, nil
+}
+
+// objectKey identifies an object by its kind, namespace and name
+func objectKey(v runtime.Object) string {
+	typeMeta, objectMeta := getObjectMeta(v)
+	return typeMeta.Kind + "/" + objectMeta.Namespace + "/" + objectMeta.Name
+}
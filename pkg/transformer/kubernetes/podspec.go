@@ -7,7 +7,6 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 	api "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -15,6 +14,11 @@ import (
 
 type PodSpec struct {
 	api.PodSpec
+
+	// err is the first error met while applying the options
+	err error
+	// annotations are the annotations of the pod template set by the options
+	annotations map[string]string
 }
 
 type PodSpecOption func(*PodSpec)
@@ -35,7 +39,8 @@ func AddContainer(service kobject.ServiceConfig, opt kobject.ConvertOptions) Pod
 
 		envs, err := ConfigEnvs(name, service, opt)
 		if err != nil {
-			panic("Unable to load env variables")
+			podSpec.setErr(errors.Wrap(err, "Unable to load env variables"))
+			return
 		}
 
 		podSpec.Containers = append(podSpec.Containers, api.Container{
@@ -52,6 +57,23 @@ func AddContainer(service kobject.ServiceConfig, opt kobject.ConvertOptions) Pod
 	}
 }
 
+// InitContainers appends the init containers which are not in the pod yet
+func InitContainers(containers []api.Container) PodSpecOption {
+	return func(podSpec *PodSpec) {
+		for _, container := range containers {
+			found := false
+			for _, initContainer := range podSpec.InitContainers {
+				if initContainer.Name == container.Name {
+					found = true
+				}
+			}
+			if !found {
+				podSpec.InitContainers = append(podSpec.InitContainers, container)
+			}
+		}
+	}
+}
+
 func ImagePullSecrets(pullSecret string) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets,
@@ -62,13 +84,13 @@ func ImagePullSecrets(pullSecret string) PodSpecOption {
 	}
 }
 
-func TerminationGracePeriodSeconds(name string, service kobject.ServiceConfig) PodSpecOption {
+func TerminationGracePeriodSeconds(name string, service kobject.ServiceConfig, report *kobject.Report) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		var err error
 		if service.StopGracePeriod != "" {
 			podSpec.TerminationGracePeriodSeconds, err = DurationStrToSecondsInt(service.StopGracePeriod)
 			if err != nil {
-				log.Warningf("Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
+				report.Warn(name, "stop_grace_period", "Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
 			}
 		}
 	}
@@ -116,8 +138,17 @@ func ResourcesRequests(service kobject.ServiceConfig) PodSpecOption {
 	}
 }
 
+// DeviceResources adds the GPUs and the device plugin resource of a service to the limits of its container
+func DeviceResources(service kobject.ServiceConfig, opt kobject.ConvertOptions) PodSpecOption {
+	return func(podSpec *PodSpec) {
+		if len(podSpec.Containers) > 0 {
+			addResourceLimits(&podSpec.Containers[len(podSpec.Containers)-1], ConfigDeviceResources(service, opt))
+		}
+	}
+}
+
 // Configure SecurityContext
-func SecurityContext(name string, service kobject.ServiceConfig) PodSpecOption {
+func SecurityContext(name string, service kobject.ServiceConfig, report *kobject.Report) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		// Configure resource reservations
 		podSecurityContext := &api.PodSecurityContext{}
@@ -127,7 +158,7 @@ func SecurityContext(name string, service kobject.ServiceConfig) PodSpecOption {
 			if service.Pid == "host" {
 				// podSecurityContext.HostPID = true
 			} else {
-				log.Warningf("Ignoring PID key for service \"%v\". Invalid value \"%v\".", name, service.Pid)
+				report.Warn(name, "pid", "Ignoring PID key for service \"%v\". Invalid value \"%v\".", name, service.Pid)
 			}
 		}
 
@@ -136,15 +167,21 @@ func SecurityContext(name string, service kobject.ServiceConfig) PodSpecOption {
 			podSecurityContext.SupplementalGroups = service.GroupAdd
 		}
 
+		podSecurityContext.Sysctls = ConfigSysctls(name, service, report)
+		podSecurityContext.FSGroup = ConfigFSGroup(name, service, report)
+
 		// Setup security context
 		securityContext := &api.SecurityContext{}
 		if service.Privileged {
 			securityContext.Privileged = &service.Privileged
 		}
+		if service.ReadOnly {
+			securityContext.ReadOnlyRootFilesystem = &service.ReadOnly
+		}
 		if service.User != "" {
 			uid, err := strconv.ParseInt(service.User, 10, 64)
 			if err != nil {
-				log.Warn("Ignoring user directive. User to be specified as a UID (numeric).")
+				report.Warn(name, "user", "Ignoring user directive. User to be specified as a UID (numeric).")
 			} else {
 				securityContext.RunAsUser = &uid
 			}
@@ -158,9 +195,19 @@ func SecurityContext(name string, service kobject.ServiceConfig) PodSpecOption {
 			securityContext.Capabilities = capabilities
 		}
 
-		// update template only if securityContext is not empty
-		if *securityContext != (api.SecurityContext{}) {
-			podSpec.Containers[0].SecurityContext = securityContext
+		// the container of the service is the last one added to the pod
+		if len(podSpec.Containers) > 0 {
+			container := &podSpec.Containers[len(podSpec.Containers)-1]
+
+			// set seccomp, SELinux, no-new-privileges and AppArmor from security_opt
+			if appArmorProfile := ConfigSecurityOpts(name, service, securityContext, report); appArmorProfile != "" {
+				podSpec.setAnnotation(AppArmorAnnotationPrefix+container.Name, appArmorProfile)
+			}
+
+			// update template only if securityContext is not empty
+			if *securityContext != (api.SecurityContext{}) {
+				container.SecurityContext = securityContext
+			}
 		}
 		if !reflect.DeepEqual(*podSecurityContext, api.PodSecurityContext{}) {
 			podSpec.SecurityContext = podSecurityContext
@@ -232,7 +279,7 @@ func SetPorts(name string, service kobject.ServiceConfig) PodSpecOption {
 func ImagePullPolicy(name string, service kobject.ServiceConfig) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		if policy, err := GetImagePullPolicy(name, service.ImagePullPolicy); err != nil {
-			panic(err)
+			podSpec.setErr(err)
 		} else {
 			for i := range podSpec.Containers {
 				podSpec.Containers[i].ImagePullPolicy = policy
@@ -245,7 +292,7 @@ func ImagePullPolicy(name string, service kobject.ServiceConfig) PodSpecOption {
 func RestartPolicy(name string, service kobject.ServiceConfig) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		if restart, err := GetRestartPolicy(name, service.Restart); err != nil {
-			panic(err)
+			podSpec.setErr(err)
 		} else {
 			podSpec.RestartPolicy = restart
 		}
@@ -269,6 +316,22 @@ func DomainName(service kobject.ServiceConfig) PodSpecOption {
 	}
 }
 
+func HostAliases(name string, service kobject.ServiceConfig, report *kobject.Report) PodSpecOption {
+	return func(podSpec *PodSpec) {
+		// the containers of a pod share its /etc/hosts
+		podSpec.HostAliases = append(podSpec.HostAliases, GetHostAliases(name, service.ExtraHosts, report)...)
+	}
+}
+
+func DNSConfig(name string, service kobject.ServiceConfig, report *kobject.Report) PodSpecOption {
+	return func(podSpec *PodSpec) {
+		if policy, dnsConfig := GetDNSConfig(name, service, report); dnsConfig != nil {
+			podSpec.DNSPolicy = policy
+			podSpec.DNSConfig = dnsConfig
+		}
+	}
+}
+
 func LivenessProbe(service kobject.ServiceConfig) PodSpecOption {
 	return func(podSpec *PodSpec) {
 		// Configure the HealthCheck
@@ -277,21 +340,22 @@ func LivenessProbe(service kobject.ServiceConfig) PodSpecOption {
 			probe := api.Probe{}
 
 			if len(service.HealthChecks.Liveness.Test) > 0 {
-				probe.Handler = api.Handler{
+				probe.ProbeHandler = api.ProbeHandler{
 					Exec: &api.ExecAction{
 						Command: service.HealthChecks.Liveness.Test,
 					},
 				}
 			} else if !reflect.ValueOf(service.HealthChecks.Liveness.HTTPPath).IsZero() &&
 				!reflect.ValueOf(service.HealthChecks.Liveness.HTTPPort).IsZero() {
-				probe.Handler = api.Handler{
+				probe.ProbeHandler = api.ProbeHandler{
 					HTTPGet: &api.HTTPGetAction{
 						Path: service.HealthChecks.Liveness.HTTPPath,
 						Port: intstr.FromInt(int(service.HealthChecks.Liveness.HTTPPort)),
 					},
 				}
 			} else {
-				panic(errors.New("Health check must contain a command"))
+				podSpec.setErr(errors.New("Health check must contain a command"))
+				return
 			}
 
 			probe.TimeoutSeconds = service.HealthChecks.Liveness.Timeout
@@ -315,13 +379,22 @@ func ReadinessProbe(service kobject.ServiceConfig) PodSpecOption {
 		if !reflect.DeepEqual(service.HealthChecks.Readiness, kobject.HealthCheck{}) {
 			probeHealthCheckReadiness := api.Probe{}
 			if len(service.HealthChecks.Readiness.Test) > 0 {
-				probeHealthCheckReadiness.Handler = api.Handler{
+				probeHealthCheckReadiness.ProbeHandler = api.ProbeHandler{
 					Exec: &api.ExecAction{
 						Command: service.HealthChecks.Readiness.Test,
 					},
 				}
+			} else if !reflect.ValueOf(service.HealthChecks.Readiness.HTTPPath).IsZero() &&
+				!reflect.ValueOf(service.HealthChecks.Readiness.HTTPPort).IsZero() {
+				probeHealthCheckReadiness.ProbeHandler = api.ProbeHandler{
+					HTTPGet: &api.HTTPGetAction{
+						Path: service.HealthChecks.Readiness.HTTPPath,
+						Port: intstr.FromInt(int(service.HealthChecks.Readiness.HTTPPort)),
+					},
+				}
 			} else {
-				panic(errors.New("Health check must contain a command"))
+				podSpec.setErr(errors.New("Health check must contain a command"))
+				return
 			}
 
 			probeHealthCheckReadiness.TimeoutSeconds = service.HealthChecks.Readiness.Timeout
@@ -350,3 +423,28 @@ func (podSpec *PodSpec) Append(ops ...PodSpecOption) *PodSpec {
 func (podSpec *PodSpec) Get() api.PodSpec {
 	return podSpec.PodSpec
 }
+
+// Err returns the first error met while applying the options
+func (podSpec *PodSpec) Err() error {
+	return podSpec.err
+}
+
+// Annotations returns the annotations of the pod template set by the options
+func (podSpec *PodSpec) Annotations() map[string]string {
+	return podSpec.annotations
+}
+
+// setAnnotation sets an annotation of the pod template
+func (podSpec *PodSpec) setAnnotation(key, value string) {
+	if podSpec.annotations == nil {
+		podSpec.annotations = map[string]string{}
+	}
+	podSpec.annotations[key] = value
+}
+
+// setErr records an error of an option, the first one is kept
+func (podSpec *PodSpec) setErr(err error) {
+	if podSpec.err == nil {
+		podSpec.err = err
+	}
+}
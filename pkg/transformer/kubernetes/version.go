@@ -0,0 +1,203 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// appArmorFieldSince is the first Kubernetes minor version with the appArmorProfile field of the security contexts,
+// which replaces the AppArmor annotations
+const appArmorFieldSince = 30
+
+// kindVersion is a GroupVersion of a kind and the first Kubernetes minor version serving it
+type kindVersion struct {
+	apiVersion string
+	since      int
+}
+
+// kindVersions lists the GroupVersions of the generated kinds which aren't served by every Kubernetes version,
+// the newest first. The other kinds keep the GroupVersion they are created with.
+var kindVersions = map[string][]kindVersion{
+	"Deployment":              {{"apps/v1", 9}},
+	"DaemonSet":               {{"apps/v1", 9}},
+	"StatefulSet":             {{"apps/v1", 9}},
+	"ReplicaSet":              {{"apps/v1", 9}},
+	"CronJob":                 {{"batch/v1", 21}, {"batch/v1beta1", 8}},
+	"Ingress":                 {{"networking.k8s.io/v1", 19}, {"extensions/v1beta1", 1}},
+	"NetworkPolicy":           {{"networking.k8s.io/v1", 7}},
+	"PodDisruptionBudget":     {{"policy/v1", 21}, {"policy/v1beta1", 5}},
+	"HorizontalPodAutoscaler": {{"autoscaling/v2", 23}, {"autoscaling/v2beta2", 12}},
+}
+
+// ParseKubeVersion returns the minor version of a Kubernetes version like 1.21, v1.21 or 1.21.3
+func ParseKubeVersion(version string) (int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, errors.Errorf("invalid Kubernetes version %q, expected a version like 1.21", version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return 0, errors.Errorf("invalid Kubernetes version %q, expected a version like 1.21", version)
+	}
+	return minor, nil
+}
+
+// APIVersion returns the GroupVersion of a kind for a Kubernetes version, the latest one if the version is empty.
+// It fails if the kind isn't served by the Kubernetes version.
+func APIVersion(kind string, kubeVersion string) (string, error) {
+	versions := kindVersions[kind]
+	if len(versions) == 0 {
+		return "", nil
+	}
+	if kubeVersion == "" {
+		return versions[0].apiVersion, nil
+	}
+	minor, err := ParseKubeVersion(kubeVersion)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if minor >= v.since {
+			return v.apiVersion, nil
+		}
+	}
+	oldest := versions[len(versions)-1]
+	return "", errors.Errorf("%s can't be generated for Kubernetes %s, it requires %s from Kubernetes 1.%d", kind, kubeVersion, oldest.apiVersion, oldest.since)
+}
+
+// SetAPIVersions sets the GroupVersion of the objects for a Kubernetes version
+func SetAPIVersions(objs []runtime.Object, kubeVersion string) error {
+	for _, obj := range objs {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		apiVersion, err := APIVersion(kind, kubeVersion)
+		if err != nil {
+			return err
+		}
+		if apiVersion == "" {
+			continue
+		}
+		groupVersion, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid GroupVersion %s of %s", apiVersion, kind)
+		}
+		if _, err := convertToVersion(obj, metav1.GroupVersion{Group: groupVersion.Group, Version: groupVersion.Version}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAppArmorProfiles moves the AppArmor profiles of the containers from the annotations of the pod templates
+// to the appArmorProfile field of their security contexts, for the Kubernetes versions having the field.
+// The Go types of the objects don't have the field, the objects using it are replaced by unstructured ones.
+func SetAppArmorProfiles(objs []runtime.Object, kubeVersion string) error {
+	if kubeVersion != "" {
+		minor, err := ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return err
+		}
+		if minor < appArmorFieldSince {
+			return nil
+		}
+	}
+
+	for i, obj := range objs {
+		if _, ok := obj.(*unstructured.Unstructured); ok {
+			continue
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert %s", obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+
+		// the path of the pod template, a Pod is its own template
+		var path []string
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "Pod":
+		case "CronJob":
+			path = []string{"spec", "jobTemplate", "spec", "template"}
+		default:
+			path = []string{"spec", "template"}
+		}
+		template, found, err := unstructured.NestedMap(content, path...)
+		if err != nil || !found {
+			continue
+		}
+
+		annotations, _, _ := unstructured.NestedStringMap(template, "metadata", "annotations")
+		containers, _, _ := unstructured.NestedSlice(template, "spec", "containers")
+		moved := false
+		for key, value := range annotations {
+			if !strings.HasPrefix(key, AppArmorAnnotationPrefix) {
+				continue
+			}
+			profile := map[string]interface{}{}
+			switch {
+			case value == "unconfined":
+				profile["type"] = "Unconfined"
+			case value == "runtime/default":
+				profile["type"] = "RuntimeDefault"
+			default:
+				profile["type"] = "Localhost"
+				profile["localhostProfile"] = strings.TrimPrefix(value, "localhost/")
+			}
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok || container["name"] != strings.TrimPrefix(key, AppArmorAnnotationPrefix) {
+					continue
+				}
+				if err := unstructured.SetNestedMap(container, profile, "securityContext", "appArmorProfile"); err != nil {
+					return err
+				}
+			}
+			delete(annotations, key)
+			moved = true
+		}
+		if !moved {
+			continue
+		}
+
+		if len(annotations) > 0 {
+			err = unstructured.SetNestedStringMap(template, annotations, "metadata", "annotations")
+		} else {
+			unstructured.RemoveNestedField(template, "metadata", "annotations")
+		}
+		if err == nil {
+			err = unstructured.SetNestedSlice(template, containers, "spec", "containers")
+		}
+		if err == nil {
+			if len(path) == 0 {
+				content = template
+			} else {
+				err = unstructured.SetNestedMap(content, template, path...)
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to set the AppArmor profiles of %s", obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+		objs[i] = &unstructured.Unstructured{Object: content}
+	}
+	return nil
+}
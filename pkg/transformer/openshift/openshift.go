@@ -27,6 +27,7 @@ import (
 	deployapi "github.com/openshift/api/apps/v1"
 	buildapi "github.com/openshift/api/build/v1"
 	imageapi "github.com/openshift/api/image/v1"
+	projectapi "github.com/openshift/api/project/v1"
 	routeapi "github.com/openshift/api/route/v1"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -255,12 +256,27 @@ func (o *OpenShift) initRoute(name string, service kobject.ServiceConfig, port i
 	return route
 }
 
+// initProject initializes OpenShift Project object
+func (o *OpenShift) initProject(name string) *projectapi.Project {
+	return &projectapi.Project{
+		TypeMeta: kapi.TypeMeta{
+			Kind:       "Project",
+			APIVersion: "project.openshift.io/v1",
+		},
+		ObjectMeta: kapi.ObjectMeta{
+			Name: name,
+		},
+	}
+}
+
 // Transform maps komposeObject to openshift objects
 // returns objects that are already sorted in the way that Services are first
 func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) ([]runtime.Object, error) {
+	o.Report = komposeObject.Report
+
 	noSupKeys := o.Kubernetes.CheckUnsupportedKey(&komposeObject, unsupportedKey)
 	for _, keyName := range noSupKeys {
-		log.Warningf("OpenShift provider doesn't support %s key - ignoring", keyName)
+		o.Report.Warn("", keyName, "OpenShift provider doesn't support %s key - ignoring", keyName)
 	}
 	// this will hold all the converted data
 	var allobjects []runtime.Object
@@ -269,6 +285,10 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 	buildRepo := opt.BuildRepo
 	buildBranch := opt.BuildBranch
 
+	if opt.WaitForDependencies {
+		kubernetes.ConfigHealthyDependencies(&komposeObject)
+	}
+
 	if komposeObject.Secrets != nil {
 		secrets, err := o.CreateSecrets(komposeObject)
 		if err != nil {
@@ -279,6 +299,14 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 		}
 	}
 
+	pvcs, err := o.CreateNamedVolumePVCs(komposeObject, opt)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		allobjects = append(allobjects, pvc)
+	}
+
 	sortedKeys := kubernetes.SortedKeys(komposeObject)
 	for _, name := range sortedKeys {
 		service := komposeObject.ServiceConfigs[name]
@@ -314,14 +342,14 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 			// Build the container!
 			err := transformer.BuildDockerImage(service, name)
 			if err != nil {
-				log.Fatalf("Unable to build Docker container for service %v: %v", name, err)
+				return nil, errors.Wrapf(err, "Unable to build Docker container for service %v", name)
 			}
 
 			// Push the built container to the repo!
 			if opt.PushImage {
-				err = transformer.PushDockerImage(service, name)
+				err = transformer.PushDockerImage(service, name, o.Report)
 				if err != nil {
-					log.Fatalf("Unable to push Docker image for service %v: %v", name, err)
+					return nil, errors.Wrapf(err, "Unable to push Docker image for service %v", name)
 				}
 			}
 		}
@@ -335,7 +363,10 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 			pod := o.InitPod(name, service)
 			objects = append(objects, pod)
 		} else {
-			objects = o.CreateKubernetesObjects(name, service, opt)
+			objects, err = o.CreateKubernetesObjects(name, service, komposeObject, opt)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Unable to create Kubernetes objects for service %v", name)
+			}
 
 			if opt.CreateDeploymentConfig {
 				objects = append(objects, o.initDeploymentConfig(name, service, replica)) // OpenShift DeploymentConfigs
@@ -349,7 +380,7 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 				// Get the compose file directory
 				composeFileDir, err = transformer.GetComposeFileDir(opt.InputFiles)
 				if err != nil {
-					log.Warningf("Error %v in detecting compose file's directory.", err)
+					o.Report.Warn(name, "build", "Error %v in detecting compose file's directory.", err)
 					continue
 				}
 
@@ -396,7 +427,7 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 					objects = append(objects, svc)
 				}
 				if len(svcs) > 1 {
-					log.Warningf("Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
+					o.Report.Warn(name, "ports", "Create multiple service to avoid using mixed protocol in the same service when it's loadbalander type")
 				}
 			} else {
 				svc := o.CreateService(name, service, objects)
@@ -416,6 +447,13 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 			return nil, errors.Wrap(err, "Error transforming Kubernetes objects")
 		}
 
+		if opt.WaitForDependencies {
+			err = o.AddInitContainers(objects, kubernetes.ConfigInitContainers(name, service, komposeObject, opt))
+			if err != nil {
+				return nil, errors.Wrap(err, "Error adding init containers")
+			}
+		}
+
 		allobjects = append(allobjects, objects...)
 	}
 
@@ -423,6 +461,22 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 	o.SortServicesFirst(&allobjects)
 	o.RemoveDupObjects(&allobjects)
 	// o.FixWorkloadVersion(&allobjects)
+	if err := kubernetes.SetAPIVersions(allobjects, opt.KubeVersion); err != nil {
+		return nil, err
+	}
+	if err := kubernetes.SetAppArmorProfiles(allobjects, opt.KubeVersion); err != nil {
+		return nil, err
+	}
+
+	namespace := kubernetes.GetNamespace(komposeObject, opt)
+	kubernetes.SetNamespace(allobjects, namespace)
+	if opt.CreateNamespace {
+		if namespace == "" {
+			o.Report.Warn("", "", "No namespace is given, the Project object won't be created")
+		} else {
+			allobjects = append([]runtime.Object{o.initProject(namespace)}, allobjects...)
+		}
+	}
 
 	return allobjects, nil
 }
@@ -27,8 +27,10 @@ import (
 	"github.com/kubernetes/kompose/pkg/transformer"
 	"github.com/kubernetes/kompose/pkg/transformer/kubernetes"
 	deployapi "github.com/openshift/api/apps/v1"
+	projectapi "github.com/openshift/api/project/v1"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	kapi "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -425,3 +427,26 @@ func TestRecreateStrategyWithVolumesPresent(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateProject(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		Name:           "web",
+		ServiceConfigs: map[string]kobject.ServiceConfig{"app": newServiceConfig()},
+	}
+
+	o := OpenShift{Kubernetes: kubernetes.Kubernetes{}}
+	objects, err := o.Transform(komposeObject, kobject.ConvertOptions{CreateDeploymentConfig: true, Replicas: 1, CreateNamespace: true})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "o.Transform failed"))
+	}
+
+	project, ok := objects[0].(*projectapi.Project)
+	if !ok || project.Name != "web" {
+		t.Fatalf("Expected the Project web first, got %#v", objects[0])
+	}
+	for _, obj := range objects[1:] {
+		if namespace := obj.(kapi.Object).GetNamespace(); namespace != "web" {
+			t.Errorf("Expected namespace web on %T, got %q", obj, namespace)
+		}
+	}
+}
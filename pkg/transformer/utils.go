@@ -91,7 +91,6 @@ func ParseVolume(volume string) (name, host, container, mode string, err error)
 	// See https://github.com/kubernetes/kompose/issues/176
 	// Otherwise, check to see if "rw" or "ro" has been passed
 	if possibleAccessMode == "z" || possibleAccessMode == "Z" {
-		log.Warnf("Volume mount \"%s\" will be mounted without labeling support. :z or :Z not supported", volume)
 		mode = ""
 		volumeStrings = volumeStrings[:len(volumeStrings)-1]
 	} else if possibleAccessMode == "rw" || possibleAccessMode == "ro" {
@@ -308,13 +307,13 @@ func BuildDockerImage(service kobject.ServiceConfig, name string) error {
 }
 
 // PushDockerImage pushes docker image
-func PushDockerImage(service kobject.ServiceConfig, serviceName string) error {
+func PushDockerImage(service kobject.ServiceConfig, serviceName string, report *kobject.Report) error {
 	log.Debugf("Pushing Docker image '%s'", service.Image)
 
 	// Don't do anything if service.Image is blank, but at least WARN about it
 	// lse, let's push the image
 	if service.Image == "" {
-		log.Warnf("No image name has been passed for service %s, skipping pushing to repository", serviceName)
+		report.Warn(serviceName, "image", "No image name has been passed for service %s, skipping pushing to repository", serviceName)
 		return nil
 	}
 